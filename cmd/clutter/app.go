@@ -63,9 +63,15 @@ var (
 			&indexFlag,
 		},
 		Commands: []*cli.Command{
+			&exportCommand,
+			&graphCommand,
+			&htmlCommand,
 			&indexCommand,
 			&lintCommand,
+			&lspCommand,
+			&renameCommand,
 			&searchCommand,
+			&serveCommand,
 			&resolveCommand,
 			&versionCommand,
 		},
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/cluttercode/clutter/internal/pkg/tagsfile"
+)
+
+var (
+	exportOpts = struct {
+		format, file string
+	}{
+		format: tagsfile.FormatCtags,
+		file:   "-",
+	}
+
+	exportCommand = cli.Command{
+		Name:  "export",
+		Usage: "write the index as a tags file for editors",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "format",
+				Usage:       fmt.Sprintf("%s or %s", tagsfile.FormatCtags, tagsfile.FormatEtags),
+				Value:       exportOpts.format,
+				Destination: &exportOpts.format,
+			},
+			&cli.StringFlag{
+				Name:        "file",
+				Aliases:     []string{"f"},
+				Usage:       "write to this file. - for stdout",
+				Value:       exportOpts.file,
+				Destination: &exportOpts.file,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 0 {
+				return fmt.Errorf("no arguments expected")
+			}
+
+			idx, err := readIndex(c)
+			if err != nil {
+				return fmt.Errorf("read index: %w", err)
+			}
+
+			var w io.Writer = os.Stdout
+
+			if exportOpts.file != "-" {
+				fp, err := os.Create(exportOpts.file)
+				if err != nil {
+					return err // do not wrap
+				}
+
+				defer fp.Close()
+
+				w = fp
+			}
+
+			if err := tagsfile.Write(w, exportOpts.format, idx, ioutil.ReadFile); err != nil {
+				return fmt.Errorf("export: %w", err)
+			}
+
+			return nil
+		},
+	}
+)
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/cluttercode/clutter/internal/pkg/graph"
+
+	"github.com/cluttercode/clutter/pkg/strmatcher"
+)
+
+var (
+	graphOpts = struct {
+		format, name, path string
+		depth              int
+	}{
+		format: graph.FormatDOT,
+		depth:  1,
+	}
+
+	graphCommand = cli.Command{
+		Name:  "graph",
+		Usage: "print the graph of tags, the files they appear in, and their references",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "format",
+				Usage:       fmt.Sprintf("%s, %s or %s", graph.FormatDOT, graph.FormatMermaid, graph.FormatJSON),
+				Value:       graphOpts.format,
+				Destination: &graphOpts.format,
+			},
+			&cli.StringFlag{
+				Name:        "name",
+				Usage:       "only tags whose names match this glob, and the nodes around them",
+				Destination: &graphOpts.name,
+			},
+			&cli.IntFlag{
+				Name:        "depth",
+				Usage:       "with --name, include nodes up to this many edges away from the matching tags",
+				Value:       graphOpts.depth,
+				Destination: &graphOpts.depth,
+			},
+			&cli.StringFlag{
+				Name:        "path",
+				Usage:       "only tags in paths that start with this prefix",
+				Destination: &graphOpts.path,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 0 {
+				return fmt.Errorf("no arguments expected")
+			}
+
+			opts := graph.Options{PathPrefix: graphOpts.path, Depth: graphOpts.depth}
+
+			if graphOpts.name != "" {
+				m, err := strmatcher.CompileGlobMatcher(graphOpts.name)
+				if err != nil {
+					return fmt.Errorf("name: %w", err)
+				}
+
+				opts.Name = m
+			}
+
+			idx, err := readIndex(c)
+			if err != nil {
+				return fmt.Errorf("read index: %w", err)
+			}
+
+			g := graph.Build(z.Named("graph"), idx, opts)
+
+			if err := graph.Write(os.Stdout, graphOpts.format, g); err != nil {
+				return fmt.Errorf("graph: %w", err)
+			}
+
+			return nil
+		},
+	}
+)
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/cluttercode/clutter/internal/pkg/site"
+)
+
+var (
+	htmlOpts = struct {
+		dir     string
+		context int
+	}{
+		dir:     "site",
+		context: 2,
+	}
+
+	htmlCommand = cli.Command{
+		Name:  "html",
+		Usage: "generate a static site for browsing tags",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "output-dir",
+				Aliases:     []string{"o"},
+				Usage:       "directory to write the site to",
+				Value:       htmlOpts.dir,
+				Destination: &htmlOpts.dir,
+			},
+			&cli.IntFlag{
+				Name:        "context",
+				Aliases:     []string{"C"},
+				Usage:       "lines of source shown around each tag",
+				Value:       htmlOpts.context,
+				Destination: &htmlOpts.context,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 0 {
+				return fmt.Errorf("no arguments expected")
+			}
+
+			idx, err := readIndex(c)
+			if err != nil {
+				return fmt.Errorf("read index: %w", err)
+			}
+
+			if err := site.Generate(z.Named("site"), idx, htmlOpts.dir, site.Options{Context: htmlOpts.context, Bracket: cfg.Scanner.Bracket}, ioutil.ReadFile); err != nil {
+				return fmt.Errorf("html: %w", err)
+			}
+
+			return nil
+		},
+	}
+)
@@ -2,13 +2,13 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"time"
 
 	cli "github.com/urfave/cli/v2"
 
 	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/indexer"
 	"github.com/cluttercode/clutter/internal/pkg/parser"
 	"github.com/cluttercode/clutter/internal/pkg/scanner"
 )
@@ -16,6 +16,8 @@ import (
 var (
 	indexOpts = struct {
 		watch, noINotify, print bool
+		git, full               bool
+		rev                     string
 		interval                time.Duration
 	}{
 		interval: 30 * time.Second,
@@ -45,33 +47,85 @@ var (
 				Aliases:     []string{"p"},
 				Destination: &indexOpts.print,
 			},
+			&cli.BoolFlag{
+				Name:        "git",
+				Usage:       "scan only files tracked by git",
+				Destination: &indexOpts.git,
+			},
+			&cli.BoolFlag{
+				Name:        "full",
+				Aliases:     []string{"f"},
+				Usage:       "rescan all files, even if unchanged since last indexed",
+				Destination: &indexOpts.full,
+			},
+			&cli.StringFlag{
+				Name:        "rev",
+				Usage:       "scan the tree at the given git revision instead of the working tree",
+				Destination: &indexOpts.rev,
+			},
 		},
 		Aliases: []string{"i"},
 		Usage:   "generate index database",
 		Action: func(c *cli.Context) error {
+			if indexOpts.watch && indexOpts.rev != "" {
+				return fmt.Errorf("--watch and --rev are mutually exclusive")
+			}
+
+			if indexOpts.git {
+				cfg.Scanner.Source = scanner.SourceGit
+			}
+
+			// previous index, for incremental updates. See [# incremental-index #].
+			var prev *index.Index
+
+			if !indexOpts.full && indexOpts.rev == "" && opts.indexPath != "stdout" && opts.indexPath != "-" {
+				var err error
+				if prev, err = index.ReadFile(opts.indexPath); err != nil {
+					z.Infow("cannot use previous index, rescanning all files", "err", err)
+					prev = nil
+				}
+			}
+
 			scan := func() error {
 				z.Info("scanning")
 
-				scan, err := scanner.NewScanner(z.Named("scanner"), cfg.Scanner)
-				if err != nil {
-					return fmt.Errorf("new scanner: %w", err)
-				}
+				var idx *index.Index
 
-				elems, err := scan(".", func(e *scanner.RawElement) error {
-					z.Infow("found", "element", e)
-					return nil
-				})
+				if indexOpts.rev != "" {
+					scan, err := newScanner(indexOpts.rev)
+					if err != nil {
+						return fmt.Errorf("new scanner: %w", err)
+					}
 
-				if err != nil {
-					return fmt.Errorf("scan: %w", err)
-				}
+					elems, err := scan(".", func(e *scanner.RawElement) error {
+						z.Infow("found", "element", e)
+						return nil
+					})
 
-				ents, err := parser.ParseElements(elems)
-				if err != nil {
-					return fmt.Errorf("parser: %w", err)
-				}
+					if err != nil {
+						return fmt.Errorf("scan: %w", err)
+					}
+
+					ents, err := parser.ParseElements(elems)
+					if err != nil {
+						return fmt.Errorf("parser: %w", err)
+					}
+
+					idx = index.NewIndex(ents)
+				} else {
+					var (
+						stats *indexer.Stats
+						err   error
+					)
 
-				idx := index.NewIndex(ents)
+					if idx, stats, err = indexer.Update(z.Named("indexer"), scannerConfigWithoutIndex(), ".", prev); err != nil {
+						return err
+					}
+
+					z.Infow("indexed", "scanned", stats.Scanned, "unchanged", stats.Unchanged, "removed", stats.Removed)
+
+					prev = idx
+				}
 
 				meta := fmt.Sprintf("%s %s", version, commit)
 
@@ -88,8 +142,6 @@ var (
 				return nil
 			}
 
-			errRefresh := fmt.Errorf("refresh")
-
 			for {
 				if err := scan(); err != nil {
 					return fmt.Errorf("scan: %w", err)
@@ -99,78 +151,29 @@ var (
 					return nil
 				}
 
-				watcher, err := fsnNewWatcher()
-				if err != nil {
-					return fmt.Errorf("watcher: %w", err)
-				}
-
-				filter, err := scanner.NewFilter(z, cfg.Scanner)
-				if err != nil {
-					z.Panicw("new filter error", "err", err)
-				}
-
-				if err := filepath.Walk(".", func(path string, fi os.FileInfo, err error) error {
-					if err != nil || !fi.Mode().IsDir() {
-						return err
-					}
-
-					ok, err := filter(path, fi)
-
-					if ok {
-						z.Debugw("watching", "path", path)
-
-						if err := watcher.Add(path); err != nil {
-							return fmt.Errorf("watcher add: %w", err)
-						}
-					}
-
+				if err := waitForChange(indexOpts.interval, indexOpts.noINotify); err != nil {
 					return err
-				}); err != nil {
-					return fmt.Errorf("watcher walk: %w", err)
 				}
+			}
+		},
+	}
+)
 
-				done := make(chan error)
-
-				never := make(chan time.Time)
-
-				go func() {
-					var poll <-chan time.Time = never
-					if indexOpts.interval != 0 {
-						poll = time.After(indexOpts.interval)
-					}
+// scannerConfigWithoutIndex returns the scanner configuration, also ignoring
+// the index file itself.
+func scannerConfigWithoutIndex() scanner.Config {
+	scfg := cfg.Scanner
 
-					if indexOpts.noINotify {
-						<-poll
-						done <- errRefresh
-						return
-					}
+	if opts.indexPath == "" {
+		return scfg
+	}
 
-					for {
-						select {
-						case <-poll:
-							done <- errRefresh
-							return
-
-						case event := <-watcher.Events:
-							if event.Op&(fsnWrite|fsnRemove|fsnRename|fsnCreate) != 0 {
-								z.Infow("file modified", "event", event.Name)
-								done <- errRefresh
-								return
-							}
-
-						case err := <-watcher.Errors:
-							done <- err
-							return
-						}
-					}
-				}()
+	ignores := scfg.Ignore
+	if len(ignores) == 0 {
+		ignores = scanner.DefaultIgnores
+	}
 
-				if err = <-done; err != nil && err != errRefresh {
-					return fmt.Errorf("watcher: %w", err)
-				}
+	scfg.Ignore = append(append([]string{}, ignores...), "/"+filepath.ToSlash(filepath.Clean(opts.indexPath)))
 
-				watcher.Close()
-			}
-		},
-	}
-)
+	return scfg
+}
@@ -3,20 +3,109 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 
 	cli "github.com/urfave/cli/v2"
 
+	"github.com/cluttercode/clutter/internal/pkg/changes"
 	"github.com/cluttercode/clutter/internal/pkg/index"
 	"github.com/cluttercode/clutter/internal/pkg/linter"
+	"github.com/cluttercode/clutter/internal/pkg/output"
+	"github.com/cluttercode/clutter/internal/pkg/parser"
+	"github.com/cluttercode/clutter/internal/pkg/renamer"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/unidiff"
 )
 
 var (
+	lintOpts = struct {
+		failOn                   string
+		reportUnusedSuppressions bool
+		since, filesFrom         string
+		jobs                     int
+		fix, dryRun              bool
+	}{}
+
 	lintCommand = cli.Command{
 		Name:    "lint",
 		Aliases: []string{"l"},
 		Usage:   "check tags against lint rules",
+		Flags: []cli.Flag{
+			newLintOutputFlag(),
+			&cli.StringFlag{
+				Name:        "fail-on",
+				Usage:       "exit with an error only if there are violations of this severity or higher: error, warning, info or none",
+				Value:       string(linter.SeverityError),
+				Destination: &lintOpts.failOn,
+			},
+			&cli.BoolFlag{
+				Name:        "report-unused-suppressions",
+				Usage:       "report nolint suppressions of rules that are not violated, as warnings",
+				Destination: &lintOpts.reportUnusedSuppressions,
+			},
+			&cli.StringFlag{
+				Name:        "since",
+				Usage:       "only check tags on lines changed since this git revision, in the working tree",
+				Destination: &lintOpts.since,
+			},
+			&cli.StringFlag{
+				Name:        "files-from",
+				Usage:       "only check tags in the files listed in this file, one per line. - for stdin",
+				Destination: &lintOpts.filesFrom,
+			},
+			&cli.IntFlag{
+				Name:        "jobs",
+				Aliases:     []string{"j"},
+				Usage:       "number of rules evaluated concurrently. 0 means number of CPUs",
+				Destination: &lintOpts.jobs,
+			},
+			&cli.BoolFlag{
+				Name:        "fix",
+				Usage:       "rewrite tags that violate rules that have fixes. Fixed violations are not reported",
+				Destination: &lintOpts.fix,
+			},
+			&cli.BoolFlag{
+				Name:        "dry-run",
+				Aliases:     []string{"n"},
+				Usage:       "with --fix, print a unified diff to stderr instead of modifying files",
+				Destination: &lintOpts.dryRun,
+			},
+		},
 		Action: func(c *cli.Context) error {
-			linter, err := linter.NewLinter(z.Named("linter"), cfg.Linter)
+			failOn, err := linter.ParseSeverity(lintOpts.failOn)
+			if err != nil {
+				return fmt.Errorf("fail-on: %w", err)
+			}
+
+			if lintOpts.dryRun && !lintOpts.fix {
+				return fmt.Errorf("--dry-run requires --fix")
+			}
+
+			if lintOpts.since != "" && lintOpts.filesFrom != "" {
+				return fmt.Errorf("--since and --files-from are mutually exclusive")
+			}
+
+			changed, err := readChanges()
+			if err != nil {
+				return err
+			}
+
+			out, err := newLintOutputWriter(func(r *output.Record) string {
+				text := fmt.Sprintf("%s: %v %s", r.Severity, r.Entry.Loc, r.Rule)
+				if r.Message != "" {
+					text += ": " + r.Message
+				}
+
+				return text
+			})
+			if err != nil {
+				return err
+			}
+
+			l, err := linter.NewLinter(z.Named("linter"), cfg.Linter)
 			if err != nil {
 				return fmt.Errorf("linter: %w", err)
 			}
@@ -28,39 +117,84 @@ var (
 
 			ctx := context.Background()
 
-			pass := true
+			violations, sups, err := lintAll(ctx, l, idx, changed)
+			if err != nil {
+				return err
+			}
 
-			if err := index.ForEach(
-				idx,
-				func(ent *index.Entry) error {
-					z.Debugw("checking", "loc", ent.Loc)
+			if lintOpts.fix {
+				paths, err := fixViolations(ctx, c, l, violations)
+				if err != nil {
+					return fmt.Errorf("fix: %w", err)
+				}
 
-					failedRulesIndices, err := linter.Lint(ctx, ent)
-					if err != nil {
+				if len(paths) > 0 {
+					// the fixed files are rescanned and linted again, so that
+					// remaining violations are reported as of the fixed tags.
+					if idx, err = reindexFiles(idx, paths); err != nil {
+						return fmt.Errorf("fix: %w", err)
+					}
+
+					if lintOpts.since != "" {
+						if changed, err = readChanges(); err != nil {
+							return err
+						}
+					}
+
+					if violations, sups, err = lintAll(ctx, l, idx, changed); err != nil {
 						return err
 					}
+				}
+			}
 
-					z := z.With("loc", ent.Loc)
+			contains := func(*index.Entry) bool { return true }
+			if changed != nil {
+				contains = changed.Contains
+			}
 
-					if len(failedRulesIndices) != 0 {
-						pass = false
+			pass := true
 
-						for i, ri := range failedRulesIndices {
-							name := linter.Rule(ri).Name
-							if name == "" {
-								name = fmt.Sprintf("#%d", i)
-							}
+			write := func(rec *output.Record, severity linter.Severity) error {
+				if severity.AtLeast(failOn) {
+					pass = false
+				}
 
-							fmt.Printf("%v %s\n", ent.Loc, name)
-						}
-					} else {
-						z.Info("entry does not violate any lint rule")
+				rec.Severity = string(severity)
+
+				return out.Write(rec)
+			}
+
+			for _, v := range violations {
+				if err := write(v.rec, v.severity); err != nil {
+					return err
+				}
+			}
+
+			if lintOpts.reportUnusedSuppressions {
+				for _, u := range sups.Unused(idx) {
+					// suppressions of entries that were not checked are not known
+					// to be unused.
+					if !contains(u.Entry) || (u.File && changed != nil && !changed.WholeFile(u.Entry.Loc.Path)) {
+						continue
+					}
+
+					pragma := "nolint"
+					if u.File {
+						pragma = "nolint-file"
 					}
 
-					return nil
-				},
-			); err != nil {
-				return fmt.Errorf("filter: %w", err)
+					rec := output.NewRecord(u.Entry, "unused-suppression")
+					rec.Kind = output.KindUnusedSuppression
+					rec.Message = fmt.Sprintf("%s %s is not violated", pragma, u.Rule)
+
+					if err := write(rec, linter.SeverityWarning); err != nil {
+						return err
+					}
+				}
+			}
+
+			if err := out.Close(); err != nil {
+				return err
 			}
 
 			if !pass {
@@ -71,3 +205,264 @@ var (
 		},
 	}
 )
+
+type violation struct {
+	rec      *output.Record
+	rule     int
+	severity linter.Severity
+}
+
+// lintAll returns the violations of all rules by the entries of idx, in
+// order, that are not suppressed. With changed, only entries it contains are
+// checked.
+func lintAll(ctx context.Context, l *linter.Linter, idx *index.Index, changed *changes.Set) ([]*violation, *linter.Suppressions, error) {
+	indexFails, err := l.LintIndex(ctx, idx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("lint index: %w", err)
+	}
+
+	// per entry rules are checked only for changed entries, while index
+	// rules are checked for all entries whose relations to other entries
+	// might have changed.
+	contains := func(*index.Entry) bool { return true }
+	touched := contains
+
+	if changed != nil {
+		contains, touched = changed.Contains, changed.Touched(idx)
+	}
+
+	sups := l.NewSuppressions()
+
+	var violations []*violation
+
+	var checked []*index.Entry
+
+	_ = index.ForEach(idx, func(ent *index.Entry) error {
+		if contains(ent) {
+			checked = append(checked, ent)
+		}
+
+		return nil
+	})
+
+	results, err := l.LintEntries(ctx, checked, lintOpts.jobs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resultsByEntry := make(map[*index.Entry]linter.Result, len(checked))
+	for i, ent := range checked {
+		resultsByEntry[ent] = results[i]
+	}
+
+	if err := index.ForEach(
+		idx,
+		func(ent *index.Entry) error {
+			result := resultsByEntry[ent]
+
+			failedRulesIndices := result.Fails
+
+			if fails := indexFails[ent]; len(fails) != 0 && touched(ent) {
+				failedRulesIndices = linter.Union(failedRulesIndices, fails)
+			}
+
+			failedRulesIndices = sups.Filter(ent, failedRulesIndices)
+
+			timedOutIndices := sups.Filter(ent, result.TimedOut)
+
+			timedOut := make(map[int]bool, len(timedOutIndices))
+			for _, ri := range timedOutIndices {
+				timedOut[ri] = true
+			}
+
+			// a rule whose entry checks timed out is reported as such, even if
+			// its index checks failed.
+			failedRulesIndices = linter.Union(failedRulesIndices, timedOutIndices)
+
+			z := z.With("loc", ent.Loc)
+
+			if len(failedRulesIndices) == 0 {
+				z.Info("entry does not violate any lint rule")
+				return nil
+			}
+
+			for _, ri := range failedRulesIndices {
+				rule := l.Rule(ri)
+
+				rec := output.NewRecord(ent, l.RuleName(ri))
+				rec.Description = rule.Description
+				rec.Kind = output.KindViolation
+
+				if timedOut[ri] {
+					rec.Kind = output.KindTimeout
+					rec.Message = fmt.Sprintf("timed out after %v", rule.Timeout)
+				} else {
+					msg, err := l.Message(ri, ent)
+					if err != nil {
+						return err
+					}
+
+					rec.Message = msg
+				}
+
+				violations = append(violations, &violation{rec: rec, rule: ri, severity: rule.Severity})
+			}
+
+			return nil
+		},
+	); err != nil {
+		return nil, nil, fmt.Errorf("filter: %w", err)
+	}
+
+	return violations, sups, nil
+}
+
+// reindexFiles returns idx with the entries of the files at paths replaced
+// by rescanning them.
+func reindexFiles(idx *index.Index, paths []string) (*index.Index, error) {
+	rescanned := make(map[string]bool, len(paths))
+
+	var ents []*index.Entry
+
+	for _, path := range paths {
+		rescanned[path] = true
+
+		fidx, err := indexFile(path, path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		ents = append(ents, fidx.Slice()...)
+	}
+
+	_ = index.ForEach(idx, func(ent *index.Entry) error {
+		if !rescanned[ent.Loc.Path] {
+			ents = append(ents, ent)
+		}
+
+		return nil
+	})
+
+	return index.NewIndex(ents), nil
+}
+
+// fixViolations rewrites the tags of violations of rules that have fixes, or
+// prints the diff with --dry-run. Fixes of all rules violated by a tag are
+// applied in order, each to the result of the previous one. It returns the
+// paths of the files that were written, which are none with --dry-run.
+func fixViolations(ctx context.Context, c *cli.Context, l *linter.Linter, violations []*violation) ([]string, error) {
+	var ents []*index.Entry
+
+	byEntry := make(map[*index.Entry][]*violation)
+
+	for _, v := range violations {
+		if v.rec.Kind != output.KindViolation || l.Rule(v.rule).Fix == nil {
+			continue
+		}
+
+		ent := v.rec.Entry
+
+		if byEntry[ent] == nil {
+			ents = append(ents, ent)
+		}
+
+		byEntry[ent] = append(byEntry[ent], v)
+	}
+
+	chs, err := renamer.Rewrite(z.Named("fixer"), cfg.Scanner, ents, func(ent *index.Entry, elem *scanner.RawElement) (string, error) {
+		// fixes rewrite the tag text, so attributes set by pragmas are not
+		// included.
+		own := *elem
+		own.NoLint, own.NoLintFile = nil, nil
+
+		curr, err := parser.ParseElement(&own)
+		if err != nil {
+			return "", fmt.Errorf("parse: %w", err)
+		}
+
+		text := ""
+
+		for _, v := range byEntry[ent] {
+			t, err := l.Fix(ctx, v.rule, curr)
+			if err != nil {
+				return "", fmt.Errorf("rule %s: %w", l.RuleName(v.rule), err)
+			}
+
+			if t == "" {
+				continue
+			}
+
+			if curr, err = parser.ParseElement(&scanner.RawElement{Text: t, Loc: elem.Loc}); err != nil {
+				return "", fmt.Errorf("rule %s: invalid fix %q: %w", l.RuleName(v.rule), t, err)
+			}
+
+			text = t
+		}
+
+		return text, nil
+	}, ioutil.ReadFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+
+	for _, ch := range chs {
+		if lintOpts.dryRun {
+			// stdout is reserved for the report, which might be machine readable.
+			fmt.Fprint(os.Stderr, unidiff.Diff("a/"+ch.Path, "b/"+ch.Path, ch.Old, ch.New, 3))
+			continue
+		}
+
+		if err := ch.Apply(); err != nil {
+			return nil, fmt.Errorf("write: %w", err)
+		}
+
+		paths = append(paths, ch.Path)
+
+		z.Infow("fixed", "path", ch.Path)
+	}
+
+	if len(chs) > 0 && !lintOpts.dryRun && hasIndex(c) {
+		z.Warn("index is out of date, run clutter index to update it")
+	}
+
+	return paths, nil
+}
+
+// readChanges returns the changes to check according to --since or
+// --files-from, or nil if all entries should be checked.
+func readChanges() (*changes.Set, error) {
+	if lintOpts.since != "" {
+		set, err := changes.Since(z.Named("changes"), cfg.Scanner, ".", lintOpts.since)
+		if err != nil {
+			return nil, fmt.Errorf("since: %w", err)
+		}
+
+		return set, nil
+	}
+
+	if lintOpts.filesFrom == "" {
+		return nil, nil
+	}
+
+	var r io.Reader = os.Stdin
+
+	if lintOpts.filesFrom != "-" {
+		fp, err := os.Open(lintOpts.filesFrom)
+		if err != nil {
+			return nil, fmt.Errorf("files-from: %w", err)
+		}
+
+		defer fp.Close()
+
+		r = fp
+	}
+
+	set, err := changes.ReadFiles(r)
+	if err != nil {
+		return nil, fmt.Errorf("files-from: %w", err)
+	}
+
+	return set, nil
+}
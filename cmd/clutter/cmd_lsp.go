@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/cluttercode/clutter/internal/pkg/linter"
+	"github.com/cluttercode/clutter/internal/pkg/lsp"
+)
+
+var (
+	lspCommand = cli.Command{
+		Name:  "lsp",
+		Usage: "run a language server over stdio, for use by IDEs",
+		Action: func(c *cli.Context) error {
+			idx, err := readIndex(c)
+			if err != nil {
+				return fmt.Errorf("read index: %w", err)
+			}
+
+			var l *linter.Linter
+
+			if len(cfg.Linter.Rules) != 0 {
+				if l, err = linter.NewLinter(z.Named("linter"), cfg.Linter); err != nil {
+					return fmt.Errorf("linter: %w", err)
+				}
+			}
+
+			s, err := lsp.NewServer(z.Named("lsp"), ".", cfg.Scanner, l, idx)
+			if err != nil {
+				return fmt.Errorf("lsp: %w", err)
+			}
+
+			return s.Serve(context.Background(), os.Stdin, os.Stdout)
+		},
+	}
+)
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/renamer"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/unidiff"
+)
+
+var (
+	renameOpts = struct {
+		loc    string
+		dryRun bool
+	}{}
+
+	renameCommand = cli.Command{
+		Name:      "rename",
+		Usage:     "rename tags in place",
+		ArgsUsage: "{--loc path:line.col new-name | old-name new-name}",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "loc",
+				Aliases:     []string{"l"},
+				Destination: &renameOpts.loc,
+				Usage:       "rename only the tags referring to the tag at path:line.col",
+			},
+			&cli.BoolFlag{
+				Name:        "dry-run",
+				Aliases:     []string{"n"},
+				Destination: &renameOpts.dryRun,
+				Usage:       "print a unified diff instead of modifying files",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			args := c.Args().Slice()
+
+			if renameOpts.loc != "" && len(args) != 1 {
+				return fmt.Errorf("expecting only new name when --loc is specified")
+			} else if renameOpts.loc == "" && len(args) != 2 {
+				return fmt.Errorf("expecting old and new names")
+			}
+
+			name := args[len(args)-1]
+
+			idx, err := readIndex(c)
+			if err != nil {
+				return fmt.Errorf("read index: %w", err)
+			}
+
+			var ents []*index.Entry
+
+			if renameOpts.loc != "" {
+				loc, err := scanner.ParseLocString(renameOpts.loc)
+				if err != nil {
+					return fmt.Errorf("loc: %w", err)
+				}
+
+				var what *index.Entry
+
+				_ = index.ForEach(idx, func(ent *index.Entry) error {
+					if ent.Loc.Contains(*loc) {
+						what = ent
+						return index.ErrStop
+					}
+
+					return nil
+				})
+
+				if what == nil {
+					return fmt.Errorf("no tag at loc")
+				}
+
+				if ents, err = renamer.TargetsAt(z.Named("resolver").With("what", what), idx, what); err != nil {
+					return fmt.Errorf("resolve: %w", err)
+				}
+			} else {
+				ents = renamer.TargetsNamed(idx, args[0])
+			}
+
+			if len(ents) == 0 {
+				return fmt.Errorf("no tags to rename")
+			}
+
+			if err := renamer.Check(idx, ents, name); err != nil {
+				return err
+			}
+
+			changes, err := renamer.Rename(z.Named("renamer"), cfg.Scanner, ents, name, ioutil.ReadFile)
+			if err != nil {
+				return err
+			}
+
+			for _, ch := range changes {
+				if renameOpts.dryRun {
+					fmt.Print(unidiff.Diff("a/"+ch.Path, "b/"+ch.Path, ch.Old, ch.New, 3))
+					continue
+				}
+
+				if err := ch.Apply(); err != nil {
+					return fmt.Errorf("write: %w", err)
+				}
+
+				z.Infow("renamed", "path", ch.Path)
+			}
+
+			if !renameOpts.dryRun && hasIndex(c) {
+				z.Warn("index is out of date, run clutter index to update it")
+			}
+
+			return nil
+		},
+	}
+)
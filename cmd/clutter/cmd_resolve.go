@@ -6,7 +6,9 @@ import (
 
 	cli "github.com/urfave/cli/v2"
 
+	"github.com/cluttercode/clutter/internal/pkg/federation"
 	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/output"
 	"github.com/cluttercode/clutter/internal/pkg/resolver"
 	"github.com/cluttercode/clutter/internal/pkg/scanner"
 	"github.com/cluttercode/clutter/pkg/zlog"
@@ -54,12 +56,18 @@ var (
 				Destination: &resolveOpts.locFromStdin,
 				Usage:       "read file at loc from stdin",
 			},
+			newOutputFlag(),
 		},
 		Action: func(c *cli.Context) error {
 			if resolveOpts.next && resolveOpts.prev {
 				return fmt.Errorf("--prev and --next are mutually exclusive")
 			}
 
+			out, err := newOutputWriter(func(r *output.Record) string { return r.Entry.String() })
+			if err != nil {
+				return err
+			}
+
 			loc, err := scanner.ParseLocString(resolveOpts.loc)
 			if err != nil {
 				return fmt.Errorf("loc: %w", err)
@@ -71,12 +79,15 @@ var (
 				what        *index.Entry // located tag
 			)
 
-			filter, err := scanner.NewFilter(z, cfg.Scanner)
+			filter, err := scanner.NewFilter(z, cfg.Scanner, ".")
 			if err != nil {
 				return fmt.Errorf("new filter: %w", err)
 			}
 
-			if ok, _ := filter(loc.Path, nil); ok && (resolveOpts.locFromStdin || !hasIndex(c)) {
+			// locs in other repos' indexes are not local files.
+			external := federation.RepoOf(cfg.Repos, loc.Path) != nil
+
+			if ok, _ := filter(loc.Path, nil); ok && !external && (resolveOpts.locFromStdin || !hasIndex(c)) {
 				locPath := loc.Path
 
 				if resolveOpts.locFromStdin {
@@ -121,7 +132,7 @@ var (
 			idx := idxAtLoc
 
 			if !skipFullIdx {
-				idx1, err := readIndex(c)
+				idx1, err := readFederatedIndex(c)
 				if err != nil {
 					return fmt.Errorf("read index: %w", err)
 				}
@@ -179,7 +190,18 @@ var (
 				return fmt.Errorf("resolver: %w", err)
 			}
 
-			return index.WriteEntries(os.Stdout, index.NewIndex(ents))
+			// the default output is in the index format, as it always was.
+			if outputOpts.format == output.FormatDefault {
+				return index.WriteEntries(os.Stdout, index.NewIndex(ents))
+			}
+
+			for _, ent := range index.NewIndex(ents).Slice() {
+				if err := out.Write(output.NewRecord(ent, "")); err != nil {
+					return err
+				}
+			}
+
+			return out.Close()
 		},
 	}
 )
@@ -7,10 +7,11 @@ import (
 	cli "github.com/urfave/cli/v2"
 
 	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/output"
 )
 
 var (
-	searchOpts = struct{ regexp, glob bool }{}
+	searchOpts = struct{ regexp, glob, query bool }{}
 
 	searchCommand = cli.Command{
 		Name:    "search",
@@ -27,10 +28,17 @@ var (
 				Aliases:     []string{"g", "gl"},
 				Destination: &searchOpts.glob,
 			},
+			&cli.BoolFlag{
+				Name:        "query",
+				Aliases:     []string{"q"},
+				Usage:       "arguments are a query expression, such as: name~^api- and (lang=go or lang=py) and not deprecated",
+				Destination: &searchOpts.query,
+			},
+			newOutputFlag(),
 		},
 		Action: func(c *cli.Context) error {
-			if searchOpts.glob && searchOpts.regexp {
-				return fmt.Errorf("--glob and --regexp are mutually exclusive")
+			if n := countTrue(searchOpts.glob, searchOpts.regexp, searchOpts.query); n > 1 {
+				return fmt.Errorf("--glob, --regexp and --query are mutually exclusive")
 			}
 
 			var (
@@ -42,9 +50,16 @@ var (
 				attrs["search"] = "glob"
 			} else if searchOpts.regexp {
 				attrs["search"] = "regexp"
+			} else if searchOpts.query {
+				attrs["search"] = "query"
+				name = strings.Join(c.Args().Slice(), " ")
 			}
 
 			for _, arg := range c.Args().Slice() {
+				if searchOpts.query {
+					break
+				}
+
 				parts := strings.SplitN(arg, "=", 2)
 
 				k := parts[0]
@@ -71,6 +86,11 @@ var (
 				attrs["search"] = "exact"
 			}
 
+			out, err := newOutputWriter(func(r *output.Record) string { return r.Entry.String() })
+			if err != nil {
+				return err
+			}
+
 			ent := index.Entry{Name: name, Attrs: attrs}
 			z.Infow("using matcher", "ent", ent)
 
@@ -79,27 +99,35 @@ var (
 				return fmt.Errorf("matcher: %w", err)
 			}
 
-			idx, err := readIndex(c)
+			idx, err := readFederatedIndex(c)
 			if err != nil {
 				return fmt.Errorf("read index: %w", err)
 			}
 
 			if err := index.ForEach(
 				idx,
-				func(ent *index.Entry) (_ error) {
+				func(ent *index.Entry) error {
 					if !matcher(ent) {
-						return
+						return nil
 					}
 
-					fmt.Println(ent.String())
-
-					return
+					return out.Write(output.NewRecord(ent, ""))
 				},
 			); err != nil {
 				return fmt.Errorf("filter: %w", err)
 			}
 
-			return nil
+			return out.Close()
 		},
 	}
 )
+
+func countTrue(bs ...bool) (n int) {
+	for _, b := range bs {
+		if b {
+			n++
+		}
+	}
+
+	return
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/indexer"
+	"github.com/cluttercode/clutter/internal/pkg/server"
+)
+
+var (
+	serveOpts = struct {
+		addr               string
+		noWatch, noINotify bool
+		interval           time.Duration
+	}{
+		addr:     "localhost:7777",
+		interval: 30 * time.Second,
+	}
+
+	serveCommand = cli.Command{
+		Name:  "serve",
+		Usage: "serve a JSON HTTP API and a browsing UI, keeping the index up to date in memory",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        "addr",
+				Usage:       "address to listen on",
+				Value:       serveOpts.addr,
+				Destination: &serveOpts.addr,
+			},
+			&cli.BoolFlag{
+				Name:        "no-watch",
+				Usage:       "do not update the index when files change",
+				Destination: &serveOpts.noWatch,
+			},
+			&cli.DurationFlag{
+				Name:        "poll-interval",
+				Aliases:     []string{"pi", "ival"},
+				Value:       serveOpts.interval,
+				Destination: &serveOpts.interval,
+			},
+			&cli.BoolFlag{
+				Name:        "no-inotify",
+				Aliases:     []string{"nin"},
+				Destination: &serveOpts.noINotify,
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 0 {
+				return fmt.Errorf("no arguments expected")
+			}
+
+			// the index file, if any, is only used to avoid rescanning unchanged
+			// files. It is not written.
+			prev, err := index.ReadFile(opts.indexPath)
+			if err != nil {
+				z.Infow("cannot use index file, scanning all files", "err", err)
+				prev = nil
+			}
+
+			update := func() (*index.Index, error) {
+				idx, stats, err := indexer.Update(z.Named("indexer"), scannerConfigWithoutIndex(), ".", prev)
+				if err != nil {
+					return nil, fmt.Errorf("index: %w", err)
+				}
+
+				z.Infow("indexed", "scanned", stats.Scanned, "unchanged", stats.Unchanged, "removed", stats.Removed)
+
+				prev = idx
+
+				return idx, nil
+			}
+
+			idx, err := update()
+			if err != nil {
+				return err
+			}
+
+			srv := server.New(z.Named("server"), idx)
+
+			if !serveOpts.noWatch {
+				go func() {
+					for {
+						if err := waitForChange(serveOpts.interval, serveOpts.noINotify); err != nil {
+							z.Errorw("watch failed, index will not be updated", "err", err)
+							return
+						}
+
+						idx, err := update()
+						if err != nil {
+							z.Errorw("update failed", "err", err)
+							continue
+						}
+
+						srv.SetIndex(idx)
+					}
+				}()
+			}
+
+			z.Infow("serving", "addr", serveOpts.addr)
+
+			return http.ListenAndServe(serveOpts.addr, srv.Handler())
+		},
+	}
+)
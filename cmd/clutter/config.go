@@ -8,6 +8,7 @@ import (
 
 	"gopkg.in/yaml.v2"
 
+	"github.com/cluttercode/clutter/internal/pkg/federation"
 	"github.com/cluttercode/clutter/internal/pkg/linter"
 	"github.com/cluttercode/clutter/internal/pkg/scanner"
 )
@@ -24,6 +25,8 @@ type config struct {
 	UseIndex bool           `yaml:"use-index"`
 	Scanner  scanner.Config `yaml:"scanner"`
 	Linter   linter.Config  `yaml:"linter"`
+
+	Repos []federation.Repo `yaml:"repos"` // external indexes for search and resolve.
 }
 
 var (
@@ -53,5 +56,9 @@ func loadConfig(path string) error {
 		return fmt.Errorf("invalid config file: %w", err)
 	}
 
+	if err := federation.Validate(cfg.Repos); err != nil {
+		return fmt.Errorf("invalid config file: repos: %w", err)
+	}
+
 	return nil
 }
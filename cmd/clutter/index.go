@@ -2,10 +2,12 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	cli "github.com/urfave/cli/v2"
 
+	"github.com/cluttercode/clutter/internal/pkg/federation"
 	"github.com/cluttercode/clutter/internal/pkg/parser"
 	"github.com/cluttercode/clutter/internal/pkg/scanner"
 
@@ -71,6 +73,21 @@ func readIndex(c *cli.Context) (*index.Index, error) {
 	return nil, fmt.Errorf("no index file exist")
 }
 
+// readFederatedIndex reads the index as readIndex does, and merges the
+// indexes of the configured repos into it.
+func readFederatedIndex(c *cli.Context) (*index.Index, error) {
+	idx, err := readIndex(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Repos) == 0 {
+		return idx, nil
+	}
+
+	return federation.Merge(z.Named("federation"), idx, cfg.Repos)
+}
+
 func readSpecificIndex(filename string) (*index.Index, error) {
 	if filename == "" {
 		return readAdHocIndex()
@@ -79,8 +96,18 @@ func readSpecificIndex(filename string) (*index.Index, error) {
 	return index.ReadFile(filename)
 }
 
+// newScanner returns a tree scanner according to configuration. If rev is
+// given, the tree at that git revision is scanned.
+func newScanner(rev string) (func(string, func(*scanner.RawElement) error) ([]*scanner.RawElement, error), error) {
+	if rev != "" || cfg.Scanner.Source == scanner.SourceGit {
+		return scanner.NewGitScanner(z.Named("scanner"), cfg.Scanner, rev)
+	}
+
+	return scanner.NewScanner(z.Named("scanner"), cfg.Scanner)
+}
+
 func readAdHocIndex() (*index.Index, error) {
-	scan, err := scanner.NewScanner(z.Named("scanner"), cfg.Scanner)
+	scan, err := newScanner("")
 	if err != nil {
 		return nil, fmt.Errorf("new scanner: %w", err)
 	}
@@ -99,14 +126,27 @@ func readAdHocIndex() (*index.Index, error) {
 }
 
 func indexFile(inputPath, actualPath string) (*index.Index, error) {
+	var r io.Reader = os.Stdin
+
+	if !(inputPath == "" || inputPath == "-" || inputPath == "stdin") {
+		fp, err := os.Open(inputPath)
+		if err != nil {
+			return nil, err // do not wrap
+		}
+
+		defer fp.Close()
+
+		r = fp
+	}
+
 	elems := make([]*scanner.RawElement, 0, 10)
 
-	if err := scanner.ScanFile(
+	if err := scanner.ScanReader(
 		z.Named("scan1"),
-		cfg.Scanner.Bracket,
-		inputPath,
+		cfg.Scanner,
+		actualPath, // determines language.
+		r,
 		func(elem *scanner.RawElement) error {
-			elem.Loc.Path = actualPath
 			elems = append(elems, elem)
 			return nil
 		},
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/cluttercode/clutter/internal/pkg/output"
+)
+
+var outputOpts = struct{ format string }{}
+
+// newOutputFlag returns the --output flag, shared by all commands that print
+// entries.
+func newOutputFlag() *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:        "output",
+		Aliases:     []string{"o", "format"},
+		Usage:       "output format: json, jsonl, quickfix or template=<go text/template>. default is the index format",
+		Destination: &outputOpts.format,
+	}
+}
+
+// newLintOutputFlag is newOutputFlag, with the report formats that are only
+// supported by lint.
+func newLintOutputFlag() *cli.StringFlag {
+	f := newOutputFlag()
+	f.Usage = "output format: json, jsonl, quickfix, sarif, junit or template=<go text/template>. default is loc and rule name"
+
+	return f
+}
+
+func newOutputWriter(dflt func(*output.Record) string) (output.Writer, error) {
+	if output.IsReport(outputOpts.format) {
+		return nil, fmt.Errorf("output format %q is only supported by lint", outputOpts.format)
+	}
+
+	return output.NewWriter(os.Stdout, outputOpts.format, dflt)
+}
+
+func newLintOutputWriter(dflt func(*output.Record) string) (output.Writer, error) {
+	return output.NewWriter(os.Stdout, outputOpts.format, dflt)
+}
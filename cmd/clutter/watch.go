@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+)
+
+// waitForChange returns when a file in a directory that is not ignored by the
+// scanner is modified, or after interval if it is not zero. With noINotify,
+// it only waits for interval.
+func waitForChange(interval time.Duration, noINotify bool) error {
+	errRefresh := fmt.Errorf("refresh")
+
+	watcher, err := fsnNewWatcher()
+	if err != nil {
+		return fmt.Errorf("watcher: %w", err)
+	}
+
+	defer watcher.Close()
+
+	filter, err := scanner.NewFilter(z, cfg.Scanner, ".")
+	if err != nil {
+		z.Panicw("new filter error", "err", err)
+	}
+
+	if err := filepath.Walk(".", func(path string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsDir() {
+			return err
+		}
+
+		// the filter only includes files: a directory is watched unless it is
+		// skipped.
+		if _, err := filter(path, fi); err != nil {
+			return err
+		}
+
+		z.Debugw("watching", "path", path)
+
+		if err := watcher.Add(path); err != nil {
+			return fmt.Errorf("watcher add: %w", err)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("watcher walk: %w", err)
+	}
+
+	done := make(chan error)
+
+	never := make(chan time.Time)
+
+	go func() {
+		var poll <-chan time.Time = never
+		if interval != 0 {
+			poll = time.After(interval)
+		}
+
+		if noINotify {
+			<-poll
+			done <- errRefresh
+			return
+		}
+
+		for {
+			select {
+			case <-poll:
+				done <- errRefresh
+				return
+
+			case event := <-watcher.Events:
+				if event.Op&(fsnWrite|fsnRemove|fsnRename|fsnCreate) != 0 {
+					z.Infow("file modified", "event", event.Name)
+					done <- errRefresh
+					return
+				}
+
+			case err := <-watcher.Errors:
+				done <- err
+				return
+			}
+		}
+	}()
+
+	if err = <-done; err != nil && err != errRefresh {
+		return fmt.Errorf("watcher: %w", err)
+	}
+
+	return nil
+}
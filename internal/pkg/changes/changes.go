@@ -0,0 +1,266 @@
+// Package changes determines which tags are changed, either relative to a
+// git revision, or by being in a given list of files.
+package changes
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/parser"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/gitrepo"
+	"github.com/cluttercode/clutter/pkg/unidiff"
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+// Set is a set of changed lines in files.
+type Set struct {
+	files map[string]map[int]bool // path -> changed lines. nil if the whole file is changed.
+	names map[string]bool         // names of tags that were deleted or changed.
+}
+
+func newSet() *Set {
+	return &Set{files: make(map[string]map[int]bool), names: make(map[string]bool)}
+}
+
+// ReadFiles returns a set of the whole files listed in r, one path per line.
+func ReadFiles(r io.Reader) (*Set, error) {
+	s := newSet()
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if path := strings.TrimSpace(sc.Text()); path != "" {
+			s.files[filepath.Clean(path)] = nil
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Since returns the set of lines in the working tree under root, which may
+// be anywhere in a git repository, that differ from the tree of the commit
+// rev, according to a line diff of each file. Only files tracked by git and
+// included by cfg are considered. Tags in the lines of rev that were deleted or changed are
+// scanned using cfg, so that Touched knows their names.
+func Since(z *zlog.Logger, cfg scanner.Config, root, rev string) (*Set, error) {
+	repo, rel, err := scanner.OpenGitRepo(root)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := repo.ResolveRev(rev)
+	if err != nil {
+		return nil, fmt.Errorf("git rev: %w", err)
+	}
+
+	include, err := scanner.NewGitPathFilter(z, cfg, root)
+	if err != nil {
+		return nil, err
+	}
+
+	base := make(map[string]gitrepo.Hash)
+
+	if err := repo.WalkTree(h, func(path string, mode uint32, blob gitrepo.Hash) error {
+		if path, ok := rel(path); ok && include(path, mode) {
+			base[path] = blob
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("git tree: %w", err)
+	}
+
+	ents, err := repo.ReadIndex()
+	if err != nil {
+		return nil, fmt.Errorf("git index: %w", err)
+	}
+
+	s := newSet()
+
+	readBase := func(path string) ([]byte, error) {
+		bs, err := repo.ReadBlob(base[path])
+		if err != nil {
+			return nil, fmt.Errorf("%s@%s: %w", path, rev, err)
+		}
+
+		return bs, nil
+	}
+
+	seen := make(map[string]bool, len(ents))
+
+	for _, ent := range ents {
+		p, ok := rel(ent.Path)
+		if !ok || !include(p, ent.Mode) {
+			continue
+		}
+
+		path := filepath.Join(root, filepath.FromSlash(p))
+
+		bs, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// deleted, but not staged.
+				continue
+			}
+
+			return nil, err
+		}
+
+		seen[p] = true
+
+		blob, ok := base[p]
+		if !ok {
+			z.Debugw("added", "path", path)
+
+			s.files[path] = nil
+
+			continue
+		}
+
+		if gitrepo.HashObject(gitrepo.TypeBlob, bs) == blob {
+			continue
+		}
+
+		old, err := readBase(p)
+		if err != nil {
+			return nil, err
+		}
+
+		deleted, inserted := unidiff.ChangedLines(old, bs)
+
+		z.Debugw("modified", "path", path, "deleted", len(deleted), "inserted", len(inserted))
+
+		s.files[path] = lineSet(inserted)
+
+		if err := s.addNames(z, cfg, path, old, lineSet(deleted)); err != nil {
+			return nil, err
+		}
+	}
+
+	for p := range base {
+		if seen[p] {
+			continue
+		}
+
+		path := filepath.Join(root, filepath.FromSlash(p))
+
+		z.Debugw("deleted", "path", path)
+
+		old, err := readBase(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.addNames(z, cfg, path, old, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func lineSet(ns []int) map[int]bool {
+	m := make(map[int]bool, len(ns))
+	for _, n := range ns {
+		m[n] = true
+	}
+
+	return m
+}
+
+// addNames adds the names of the tags in bs that are on any of lines, or
+// all tags if lines is nil. Tags that cannot be parsed are ignored, as they
+// are no longer there anyway.
+func (s *Set) addNames(z *zlog.Logger, cfg scanner.Config, path string, bs []byte, lines map[int]bool) error {
+	if lines != nil && len(lines) == 0 {
+		return nil
+	}
+
+	if err := scanner.ScanReader(z, cfg, path, bytes.NewReader(bs), func(elem *scanner.RawElement) error {
+		if lines != nil && !anyLine(lines, elem.Loc) {
+			return nil
+		}
+
+		ent, err := parser.ParseElement(elem)
+		if err != nil {
+			z.Debugw("ignoring unparsable deleted tag", "loc", elem.Loc, "err", err)
+			return nil
+		}
+
+		if _, search := ent.IsSearch(); !search {
+			s.names[ent.Name] = true
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("%s: scan: %w", path, err)
+	}
+
+	return nil
+}
+
+func anyLine(lines map[int]bool, loc scanner.Loc) bool {
+	for n := loc.Line; n <= loc.LastLine(); n++ {
+		if lines[n] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Contains returns true if any line of ent is changed.
+func (s *Set) Contains(ent *index.Entry) bool {
+	lines, ok := s.files[ent.Loc.Path]
+	if !ok {
+		return false
+	}
+
+	return lines == nil || anyLine(lines, ent.Loc)
+}
+
+// WholeFile returns true if all lines of the file at path are changed.
+func (s *Set) WholeFile(path string) bool {
+	lines, ok := s.files[path]
+	return ok && lines == nil
+}
+
+// Touched returns a function that returns true for entries in idx that are
+// either changed, or have the same name as a changed or deleted tag. These
+// are the entries whose relations to other entries might have changed.
+func (s *Set) Touched(idx *index.Index) func(*index.Entry) bool {
+	names := make(map[string]bool, len(s.names))
+	for name := range s.names {
+		names[name] = true
+	}
+
+	_ = index.ForEach(idx, func(ent *index.Entry) error {
+		if _, search := ent.IsSearch(); !search && s.Contains(ent) {
+			names[ent.Name] = true
+		}
+
+		return nil
+	})
+
+	return func(ent *index.Entry) bool {
+		if s.Contains(ent) {
+			return true
+		}
+
+		_, search := ent.IsSearch()
+
+		return !search && names[ent.Name]
+	}
+}
@@ -0,0 +1,125 @@
+// [# %stop! #]
+
+package changes
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(
+		[]string{"PATH=" + os.Getenv("PATH")},
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+dir,
+	)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+}
+
+func write(t *testing.T, dir, path, text string) {
+	if err := ioutil.WriteFile(filepath.Join(dir, path), []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "clutter-changes-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	git(t, dir, "init", "-q")
+
+	write(t, dir, "same", "[# same #]\n")
+	write(t, dir, "modified", "[# a #]\n[# b #]\n[# c #]\n")
+	write(t, dir, "deleted", "[# gone #]\n")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-qm", "first")
+
+	write(t, dir, "modified", "[# a #]\n[# b2 #]\n[# c #]\n")
+	write(t, dir, "added", "[# new #]\n")
+	git(t, dir, "add", "added")
+	git(t, dir, "rm", "-q", "deleted")
+
+	cfg := scanner.Config{Bracket: scanner.BracketConfig{Left: "[#", Right: "#]"}}
+
+	s, err := Since(zlog.NewNopLogger(), cfg, dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ent := func(name, path string, line int) *index.Entry {
+		return &index.Entry{Name: name, Loc: scanner.Loc{Path: filepath.Join(dir, path), Line: line, StartColumn: 1, EndColumn: 7}}
+	}
+
+	ents := []*index.Entry{
+		ent("same", "same", 1),
+		ent("a", "modified", 1),
+		ent("b2", "modified", 2),
+		ent("c", "modified", 3),
+		ent("new", "added", 1),
+		ent("b", "elsewhere", 1),
+		ent("gone", "elsewhere", 2),
+		ent("a", "elsewhere", 3),
+	}
+
+	touched := s.Touched(index.NewIndex(ents))
+
+	var contained, touchedNames []string
+
+	for _, e := range ents {
+		if s.Contains(e) {
+			contained = append(contained, e.Name)
+		}
+
+		if touched(e) {
+			touchedNames = append(touchedNames, e.Name)
+		}
+	}
+
+	if got, exp := strings.Join(contained, " "), "b2 new"; got != exp {
+		t.Errorf("contains: %q != %q", got, exp)
+	}
+
+	if got, exp := strings.Join(touchedNames, " "), "b2 new b gone"; got != exp {
+		t.Errorf("touched: %q != %q", got, exp)
+	}
+
+	if !s.WholeFile(filepath.Join(dir, "added")) || s.WholeFile(filepath.Join(dir, "modified")) {
+		t.Errorf("whole file")
+	}
+}
+
+func TestReadFiles(t *testing.T) {
+	s, err := ReadFiles(strings.NewReader("./a/b\n\n c \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, exp := range map[string]bool{"a/b": true, "c": true, "a": false} {
+		if got := s.WholeFile(path); got != exp {
+			t.Errorf("%s: %v != %v", path, got, exp)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package federation merges the indexes of other repositories into a local
+// index, so that tags can refer to tags in other repositories using the repo
+// attribute, see index.AttrRepo.
+package federation
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/parser"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+// Repo is an external index, produced by clutter index in another repository.
+type Repo struct {
+	Name string `yaml:"name"`
+
+	// Index is a local path, relative to the working directory, or a file://
+	// URL of the index file.
+	Index string `yaml:"index"`
+
+	// Prefix is prepended to the paths of the repository's entries, recording
+	// where they came from. Default: @name/.
+	Prefix string `yaml:"prefix"`
+}
+
+func (r *Repo) prefix() string {
+	if r.Prefix != "" {
+		return r.Prefix
+	}
+
+	return "@" + r.Name + "/"
+}
+
+// path returns the local path of the index file.
+func (r *Repo) path() (string, error) {
+	if !strings.Contains(r.Index, "://") {
+		return r.Index, nil
+	}
+
+	u, err := url.Parse(r.Index)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("unsupported host %q", u.Host)
+	}
+
+	return u.Path, nil
+}
+
+// Validate checks that repos have valid and unique names, and indexes.
+func Validate(repos []Repo) error {
+	names := make(map[string]bool, len(repos))
+
+	for i, r := range repos {
+		if !parser.IsValidAttrName(r.Name) {
+			return fmt.Errorf("repo #%d: invalid name %q", i, r.Name)
+		}
+
+		if names[r.Name] {
+			return fmt.Errorf("repo %s: duplicate name", r.Name)
+		}
+
+		names[r.Name] = true
+
+		if r.Index == "" {
+			return fmt.Errorf("repo %s: index must be specified", r.Name)
+		}
+
+		if _, err := r.path(); err != nil {
+			return fmt.Errorf("repo %s: index: %w", r.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Entries returns ents as they are seen from another repository: paths and
+// scopes are prefixed by the repository's prefix, and entries that do not
+// refer to yet another repository are marked as belonging to r.
+func (r *Repo) Entries(ents []*index.Entry) []*index.Entry {
+	prefix := r.prefix()
+
+	fents := make([]*index.Entry, len(ents))
+
+	for i, ent := range ents {
+		attrs := make(index.Attrs, len(ent.Attrs)+1)
+		for k, v := range ent.Attrs {
+			attrs[k] = v
+		}
+
+		if scope, ok := attrs["scope"]; ok {
+			attrs["scope"] = prefix + scope
+		}
+
+		if _, ok := attrs[index.AttrRepo]; !ok {
+			attrs[index.AttrRepo] = r.Name
+		}
+
+		loc := ent.Loc
+		loc.Path = prefix + loc.Path
+
+		fents[i] = &index.Entry{Name: ent.Name, Attrs: attrs, Loc: loc}
+	}
+
+	return fents
+}
+
+// Merge adds the entries of repos to idx. Indexes that do not exist are
+// skipped with a warning, as the repositories producing them might not be
+// available locally. It modifies idx.
+func Merge(z *zlog.Logger, idx *index.Index, repos []Repo) (*index.Index, error) {
+	if err := Validate(repos); err != nil {
+		return nil, err
+	}
+
+	for _, r := range repos {
+		path, _ := r.path()
+
+		z := z.With("repo", r.Name, "path", path)
+
+		ridx, err := index.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				z.Warn("repo index does not exist")
+				continue
+			}
+
+			return nil, fmt.Errorf("repo %s: %w", r.Name, err)
+		}
+
+		z.Infow("repo index read", "n", ridx.Size())
+
+		idx.Add(r.Entries(ridx.Slice()))
+	}
+
+	return idx, nil
+}
+
+// RepoOf returns the repo whose prefix path starts with, if any.
+func RepoOf(repos []Repo, path string) *Repo {
+	for i := range repos {
+		if strings.HasPrefix(path, repos[i].prefix()) {
+			return &repos[i]
+		}
+	}
+
+	return nil
+}
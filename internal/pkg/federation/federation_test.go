@@ -0,0 +1,115 @@
+package federation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/resolver"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		repos []Repo
+		err   bool
+	}{
+		{name: "none"},
+		{name: "path", repos: []Repo{{Name: "a", Index: "../a/.clutter/index"}}},
+		{name: "url", repos: []Repo{{Name: "a", Index: "file:///a/index"}}},
+		{name: "no name", repos: []Repo{{Index: "x"}}, err: true},
+		{name: "invalid name", repos: []Repo{{Name: "a b", Index: "x"}}, err: true},
+		{name: "no index", repos: []Repo{{Name: "a"}}, err: true},
+		{name: "duplicate", repos: []Repo{{Name: "a", Index: "x"}, {Name: "a", Index: "y"}}, err: true},
+		{name: "scheme", repos: []Repo{{Name: "a", Index: "https://example.com/index"}}, err: true},
+		{name: "host", repos: []Repo{{Name: "a", Index: "file://example.com/index"}}, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := Validate(test.repos); (err != nil) != test.err {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestEntries(t *testing.T) {
+	r := Repo{Name: "back"}
+
+	got := r.Entries([]*index.Entry{
+		{Name: "a", Loc: scanner.Loc{Path: "x/y.go", Line: 1}},
+		{Name: "b", Attrs: index.Attrs{"scope": "x/"}, Loc: scanner.Loc{Path: "x/y.go", Line: 2}},
+		{Name: "c", Attrs: index.Attrs{"repo": "front"}, Loc: scanner.Loc{Path: "z.go", Line: 1}},
+	})
+
+	exp := []string{
+		"a @back/x/y.go:1.0-0 repo=back",
+		"b @back/x/y.go:2.0-0 scope=@back/x/ repo=back",
+		"c @back/z.go:1.0-0 repo=front",
+	}
+
+	for i, ent := range got {
+		if s := ent.String(); s != exp[i] {
+			t.Errorf("%d: %q != %q", i, s, exp[i])
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clutter-federation")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "index")
+
+	if err := index.WriteFile(path, index.NewIndex([]*index.Entry{
+		{Name: "flow", Loc: scanner.Loc{Path: "auth.go", Line: 1, StartColumn: 1, EndColumn: 10}},
+	}), "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	ref := &index.Entry{Name: "flow", Attrs: index.Attrs{"repo": "back"}, Loc: scanner.Loc{Path: "docs.md", Line: 1, StartColumn: 1, EndColumn: 20}}
+	local := &index.Entry{Name: "flow", Loc: scanner.Loc{Path: "docs.md", Line: 2, StartColumn: 1, EndColumn: 10}}
+
+	idx, err := Merge(
+		zlog.NewNopLogger(),
+		index.NewIndex([]*index.Entry{ref, local}),
+		[]Repo{
+			{Name: "back", Index: "file://" + filepath.ToSlash(path), Prefix: "back/"},
+			{Name: "missing", Index: filepath.Join(dir, "missing")},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if idx.Size() != 3 {
+		t.Fatalf("unexpected size %d", idx.Size())
+	}
+
+	ents, err := resolver.ResolveList(zlog.NewNopLogger(), ref, idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ents) != 2 || ents[0].Loc.Path != "back/auth.go" || ents[1] != ref {
+		t.Errorf("unexpected resolution %v", ents)
+	}
+
+	if ents, _ := resolver.ResolveList(zlog.NewNopLogger(), local, idx); len(ents) != 1 || ents[0] != local {
+		t.Errorf("unexpected local resolution %v", ents)
+	}
+
+	if r := RepoOf([]Repo{{Name: "back", Prefix: "back/"}}, "back/auth.go"); r == nil || r.Name != "back" {
+		t.Errorf("unexpected repo of %v", r)
+	}
+}
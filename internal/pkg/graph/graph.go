@@ -0,0 +1,264 @@
+// Package graph builds the graph of relations between tags and the files
+// they appear in, and writes it in formats that can be rendered.
+package graph
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+// Node kinds.
+const (
+	KindFile   = "file"
+	KindTag    = "tag"    // all tags with the same name.
+	KindSearch = "search" // all search tags with the same pattern.
+)
+
+// Edge kinds.
+const (
+	EdgeOccurs  = "occurs"  // file -> tag or search: the tag appears in the file.
+	EdgeRefers  = "refers"  // tag -> tag: an attribute value is the name of the other tag.
+	EdgeMatches = "matches" // search -> tag: the search matches the tag.
+)
+
+type Node struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+	Count int    `json:"count"` // number of entries, or for files, of entries in them.
+}
+
+type Edge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Kind  string `json:"kind"`
+	Label string `json:"label,omitempty"` // the attribute, for EdgeRefers.
+	Count int    `json:"count"`           // number of entries the edge stands for.
+}
+
+type Graph struct {
+	Nodes []*Node `json:"nodes"`
+	Edges []*Edge `json:"edges"`
+}
+
+// Options select the part of the graph to build.
+type Options struct {
+	// PathPrefix, if not empty, excludes entries in paths that do not start
+	// with it.
+	PathPrefix string
+
+	// Name, if not nil, limits the graph to tags whose names it matches, and
+	// the nodes up to Depth edges away from them, in either direction.
+	Name  func(string) bool
+	Depth int
+}
+
+func Build(z *zlog.Logger, idx *index.Index, opts Options) *Graph {
+	b := builder{nodes: make(map[string]*Node), edges: make(map[Edge]*Edge)}
+
+	var ents, searches []*index.Entry
+
+	_ = index.ForEach(idx, func(ent *index.Entry) error {
+		if !strings.HasPrefix(ent.Loc.Path, opts.PathPrefix) {
+			return nil
+		}
+
+		if _, search := ent.IsSearch(); search {
+			searches = append(searches, ent)
+		} else {
+			ents = append(ents, ent)
+		}
+
+		return nil
+	})
+
+	names := make(map[string]bool, len(ents))
+
+	for _, ent := range ents {
+		names[ent.Name] = true
+
+		b.node(KindTag, ent.Name, ent.Name).Count++
+		b.node(KindFile, ent.Loc.Path, ent.Loc.Path).Count++
+		b.edge(KindFile+":"+ent.Loc.Path, KindTag+":"+ent.Name, EdgeOccurs, "")
+	}
+
+	for _, ent := range ents {
+		for k, v := range ent.Attrs {
+			// values of special attributes are never tag names.
+			if index.IsSpecialAttr(k) || v == ent.Name || !names[v] {
+				continue
+			}
+
+			b.edge(KindTag+":"+ent.Name, KindTag+":"+v, EdgeRefers, k)
+		}
+	}
+
+	for _, s := range searches {
+		z := z.With("loc", s.Loc)
+
+		m, err := s.Matcher()
+		if err != nil {
+			z.Warnw("invalid search tag", "err", err)
+			continue
+		}
+
+		text := searchText(s)
+
+		b.node(KindSearch, text, text).Count++
+		b.node(KindFile, s.Loc.Path, s.Loc.Path).Count++
+		b.edge(KindFile+":"+s.Loc.Path, KindSearch+":"+text, EdgeOccurs, "")
+
+		for _, ent := range ents {
+			if m(ent) {
+				b.edge(KindSearch+":"+text, KindTag+":"+ent.Name, EdgeMatches, "")
+			}
+		}
+	}
+
+	g := b.graph()
+
+	if opts.Name != nil {
+		g = g.around(opts.Name, opts.Depth)
+	}
+
+	return g
+}
+
+// searchText returns the pattern of a search tag and its attribute patterns.
+func searchText(ent *index.Entry) string {
+	pt, _ := ent.IsSearch()
+
+	parts := []string{"?" + pt, ent.Name}
+
+	keys := make([]string, 0, len(ent.Attrs))
+	for k := range ent.Attrs {
+		if !index.IsSpecialAttr(k) {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		parts = append(parts, index.AttrToString(k, ent.Attrs[k]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+type builder struct {
+	nodes map[string]*Node
+	edges map[Edge]*Edge // keyed by the edge without its count.
+}
+
+func (b *builder) node(kind, key, label string) *Node {
+	id := kind + ":" + key
+
+	n := b.nodes[id]
+	if n == nil {
+		n = &Node{ID: id, Kind: kind, Label: label}
+		b.nodes[id] = n
+	}
+
+	return n
+}
+
+func (b *builder) edge(from, to, kind, label string) {
+	key := Edge{From: from, To: to, Kind: kind, Label: label}
+
+	e := b.edges[key]
+	if e == nil {
+		e = &key
+		b.edges[key] = e
+	}
+
+	e.Count++
+}
+
+// graph returns the nodes and edges sorted, so output is stable.
+func (b *builder) graph() *Graph {
+	g := &Graph{Nodes: []*Node{}, Edges: []*Edge{}}
+
+	for _, n := range b.nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+
+	for _, e := range b.edges {
+		g.Edges = append(g.Edges, e)
+	}
+
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+
+		if a.From != b.From {
+			return a.From < b.From
+		}
+
+		if a.To != b.To {
+			return a.To < b.To
+		}
+
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+
+		return a.Label < b.Label
+	})
+
+	return g
+}
+
+// around returns the subgraph of the tags whose names match, the nodes up to
+// depth edges away from them, and the edges between all of those.
+func (g *Graph) around(match func(string) bool, depth int) *Graph {
+	keep := make(map[string]bool)
+
+	var frontier []string
+
+	for _, n := range g.Nodes {
+		if n.Kind == KindTag && match(n.Label) {
+			keep[n.ID] = true
+			frontier = append(frontier, n.ID)
+		}
+	}
+
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		next := make(map[string]bool)
+		for _, id := range frontier {
+			next[id] = true
+		}
+
+		frontier = nil
+
+		for _, e := range g.Edges {
+			for _, ends := range [][2]string{{e.From, e.To}, {e.To, e.From}} {
+				if next[ends[0]] && !keep[ends[1]] {
+					keep[ends[1]] = true
+					frontier = append(frontier, ends[1])
+				}
+			}
+		}
+	}
+
+	sub := &Graph{Nodes: []*Node{}, Edges: []*Edge{}}
+
+	for _, n := range g.Nodes {
+		if keep[n.ID] {
+			sub.Nodes = append(sub.Nodes, n)
+		}
+	}
+
+	for _, e := range g.Edges {
+		if keep[e.From] && keep[e.To] {
+			sub.Edges = append(sub.Edges, e)
+		}
+	}
+
+	return sub
+}
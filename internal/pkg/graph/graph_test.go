@@ -0,0 +1,178 @@
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/strmatcher"
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+var testIndex = index.NewIndex([]*index.Entry{
+	{Name: "cat", Attrs: index.Attrs{"see": "dog"}, Loc: scanner.Loc{Path: "a/x.go", Line: 1}},
+	{Name: "cat", Loc: scanner.Loc{Path: "b/y.go", Line: 1}},
+	{Name: "dog", Attrs: index.Attrs{"scope": "cat"}, Loc: scanner.Loc{Path: "b/y.go", Line: 2}},
+	{Name: "fish", Loc: scanner.Loc{Path: "b/z.go", Line: 1}},
+	{Name: "c*", Attrs: index.Attrs{"search": "glob"}, Loc: scanner.Loc{Path: "a/x.go", Line: 2}},
+})
+
+// edges returns the edges of g as from-kind->to, sorted.
+func edges(g *Graph) string {
+	var es []string
+
+	for _, e := range g.Edges {
+		es = append(es, fmt.Sprintf("%s-%s%s->%s", e.From, e.Kind, e.Label, e.To))
+	}
+
+	return strings.Join(es, " ")
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		exp  string
+	}{
+		{
+			name: "all",
+			exp: "file:a/x.go-occurs->search:?glob c* " +
+				"file:a/x.go-occurs->tag:cat " +
+				"file:b/y.go-occurs->tag:cat " +
+				"file:b/y.go-occurs->tag:dog " +
+				"file:b/z.go-occurs->tag:fish " +
+				"search:?glob c*-matches->tag:cat " +
+				"tag:cat-referssee->tag:dog",
+		},
+		{
+			name: "path",
+			opts: Options{PathPrefix: "b/"},
+			exp: "file:b/y.go-occurs->tag:cat " +
+				"file:b/y.go-occurs->tag:dog " +
+				"file:b/z.go-occurs->tag:fish",
+		},
+		{
+			name: "around dog",
+			opts: Options{Name: func(s string) bool { return s == "dog" }, Depth: 1},
+			exp: "file:b/y.go-occurs->tag:cat " +
+				"file:b/y.go-occurs->tag:dog " +
+				"tag:cat-referssee->tag:dog",
+		},
+		{
+			name: "around fish",
+			opts: Options{Name: func(s string) bool { return s == "fish" }},
+			exp:  "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := edges(Build(zlog.NewNopLogger(), testIndex, test.opts)); got != test.exp {
+				t.Errorf("\n%s\n!=\n%s", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	m, err := strmatcher.CompileGlobMatcher("dog")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := Build(zlog.NewNopLogger(), testIndex, Options{Name: m, Depth: 1})
+
+	tests := []struct {
+		format, exp string
+	}{
+		{
+			format: FormatDOT,
+			exp: `digraph clutter {
+  rankdir=LR;
+  "file:b/y.go" [label="b/y.go", shape=box];
+  "tag:cat" [label="cat", shape=ellipse];
+  "tag:dog" [label="dog", shape=ellipse];
+  "file:b/y.go" -> "tag:cat" [style=solid];
+  "file:b/y.go" -> "tag:dog" [style=solid];
+  "tag:cat" -> "tag:dog" [style=dashed, label="see"];
+}
+`,
+		},
+		{
+			format: FormatMermaid,
+			exp: `flowchart LR
+  n0["b/y.go"]
+  n1(["cat"])
+  n2(["dog"])
+  n0 --> n1
+  n0 --> n2
+  n1 -.->|"see"| n2
+`,
+		},
+		{
+			format: FormatJSON,
+			exp: `{
+  "nodes": [
+    {
+      "id": "file:b/y.go",
+      "kind": "file",
+      "label": "b/y.go",
+      "count": 2
+    },
+    {
+      "id": "tag:cat",
+      "kind": "tag",
+      "label": "cat",
+      "count": 2
+    },
+    {
+      "id": "tag:dog",
+      "kind": "tag",
+      "label": "dog",
+      "count": 1
+    }
+  ],
+  "edges": [
+    {
+      "from": "file:b/y.go",
+      "to": "tag:cat",
+      "kind": "occurs",
+      "count": 1
+    },
+    {
+      "from": "file:b/y.go",
+      "to": "tag:dog",
+      "kind": "occurs",
+      "count": 1
+    },
+    {
+      "from": "tag:cat",
+      "to": "tag:dog",
+      "kind": "refers",
+      "label": "see",
+      "count": 1
+    }
+  ]
+}
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var b bytes.Buffer
+
+			if err := Write(&b, test.format, g); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := b.String(); got != test.exp {
+				t.Errorf("\n%s\n!=\n%s", got, test.exp)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package graph
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+	FormatJSON    = "json"
+)
+
+// Write writes g to w in format.
+func Write(w io.Writer, format string, g *Graph) error {
+	switch format {
+	case FormatDOT:
+		return writeDOT(w, g)
+	case FormatMermaid:
+		return writeMermaid(w, g)
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(g)
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+var (
+	dotShapes = map[string]string{
+		KindFile:   "box",
+		KindTag:    "ellipse",
+		KindSearch: "diamond",
+	}
+
+	dotEdgeStyles = map[string]string{
+		EdgeOccurs:  "solid",
+		EdgeRefers:  "dashed",
+		EdgeMatches: "dotted",
+	}
+)
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotQuote(s string) string { return `"` + dotEscaper.Replace(s) + `"` }
+
+func writeDOT(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph clutter {")
+	fmt.Fprintln(bw, "  rankdir=LR;")
+
+	for _, n := range g.Nodes {
+		fmt.Fprintf(bw, "  %s [label=%s, shape=%s];\n", dotQuote(n.ID), dotQuote(n.Label), dotShapes[n.Kind])
+	}
+
+	for _, e := range g.Edges {
+		fmt.Fprintf(bw, "  %s -> %s [style=%s", dotQuote(e.From), dotQuote(e.To), dotEdgeStyles[e.Kind])
+
+		if e.Label != "" {
+			fmt.Fprintf(bw, ", label=%s", dotQuote(e.Label))
+		}
+
+		fmt.Fprintln(bw, "];")
+	}
+
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+var (
+	// node shapes, as the brackets around the label.
+	mermaidShapes = map[string][2]string{
+		KindFile:   {"[", "]"},
+		KindTag:    {"([", "])"},
+		KindSearch: {"{{", "}}"},
+	}
+
+	mermaidArrows = map[string]string{
+		EdgeOccurs:  "-->",
+		EdgeRefers:  "-.->",
+		EdgeMatches: "==>",
+	}
+
+	mermaidEscaper = strings.NewReplacer(`"`, "#quot;")
+)
+
+// writeMermaid writes a flowchart. Node IDs are replaced by sequential ones,
+// as mermaid IDs cannot contain most punctuation.
+func writeMermaid(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "flowchart LR")
+
+	ids := make(map[string]string, len(g.Nodes))
+
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+
+		shape := mermaidShapes[n.Kind]
+
+		fmt.Fprintf(bw, "  %s%s\"%s\"%s\n", ids[n.ID], shape[0], mermaidEscaper.Replace(n.Label), shape[1])
+	}
+
+	for _, e := range g.Edges {
+		label := ""
+		if e.Label != "" {
+			label = fmt.Sprintf("|\"%s\"|", mermaidEscaper.Replace(e.Label))
+		}
+
+		fmt.Fprintf(bw, "  %s %s%s %s\n", ids[e.From], mermaidArrows[e.Kind], label, ids[e.To])
+	}
+
+	return bw.Flush()
+}
@@ -15,6 +15,11 @@ type Entry struct {
 	Name  string
 	Attrs Attrs
 	Loc   scanner.Loc
+
+	// NoLint and NoLintFile are the names of the rules whose violations are
+	// suppressed by the entry itself and by its file. They are not attributes,
+	// but are written as such in tags and in the index, see AttrNoLint.
+	NoLint, NoLintFile []string
 }
 
 func (e *Entry) IsSearch() (patternType string, yes bool) {
@@ -22,6 +27,65 @@ func (e *Entry) IsSearch() (patternType string, yes bool) {
 	return
 }
 
+// Attributes that record lint suppressions, as set by the %nolint and
+// %nolint-file pragmas. Their values are comma separated rule names.
+const (
+	AttrNoLint     = "nolint"
+	AttrNoLintFile = "nolint-file"
+)
+
+// AttrRepo is the attribute naming the repository of a tag, for tags merged
+// from other repositories' indexes, or the repository of the tags referred to,
+// for local tags. Tags only refer to tags of the same repository.
+const AttrRepo = "repo"
+
+// IsSpecialAttr returns true if k is an attribute that has a meaning to
+// clutter itself, rather than one that is only given meaning by users.
+func IsSpecialAttr(k string) bool {
+	return k == "scope" || k == "search" || k == AttrRepo
+}
+
+// AttrsWithNoLint returns the attributes of e, along with its suppressions
+// as the attributes that record them.
+func (e *Entry) AttrsWithNoLint() Attrs {
+	attrs := make(Attrs, len(e.Attrs)+2)
+	for k, v := range e.Attrs {
+		attrs[k] = v
+	}
+
+	if len(e.NoLint) > 0 {
+		attrs[AttrNoLint] = strings.Join(e.NoLint, ",")
+	}
+
+	if len(e.NoLintFile) > 0 {
+		attrs[AttrNoLintFile] = strings.Join(e.NoLintFile, ",")
+	}
+
+	return attrs
+}
+
+// TakeNoLint moves the attributes that record suppressions from the
+// attributes of e to its suppressions.
+func (e *Entry) TakeNoLint() {
+	take := func(k string, rules []string) []string {
+		v, ok := e.Attrs[k]
+		if !ok {
+			return rules
+		}
+
+		delete(e.Attrs, k)
+
+		if v == "" {
+			return rules
+		}
+
+		return append(rules, strings.Split(v, ",")...)
+	}
+
+	e.NoLint = take(AttrNoLint, e.NoLint)
+	e.NoLintFile = take(AttrNoLintFile, e.NoLintFile)
+}
+
 func (e *Entry) Matcher() (func(*Entry) bool, error) {
 	pt, _ := e.IsSearch()
 
@@ -34,6 +98,15 @@ func (e *Entry) Matcher() (func(*Entry) bool, error) {
 		compile = strmatcher.CompileRegexpMatcher
 	case "glob":
 		compile = strmatcher.CompileGlobMatcher
+	case "query":
+		// the whole query is in the name, see CompileQuery.
+		for k := range e.Attrs {
+			if k != "search" {
+				return nil, fmt.Errorf("attribute %q cannot be used with a query", k)
+			}
+		}
+
+		return CompileQuery(e.Name)
 	default:
 		return nil, fmt.Errorf("unknown pattern type")
 	}
@@ -93,6 +166,10 @@ func (e *Entry) Matcher() (func(*Entry) bool, error) {
 }
 
 func (e *Entry) IsReferredBy(them *Entry) bool {
+	if e.Attrs[AttrRepo] != them.Attrs[AttrRepo] {
+		return false
+	}
+
 	mine, theirs := e.Attrs["scope"], them.Attrs["scope"]
 
 	if mine == "" {
@@ -125,7 +202,8 @@ func (e *Entry) IsReferredBy(them *Entry) bool {
 	return mine == theirs
 }
 
-func (e *Entry) String() string { return e.marshal() }
+// String formats e as an index line, but without its suppressions.
+func (e *Entry) String() string { return e.format(e.Attrs) }
 
 func (e *Entry) AttrsWithLoc() Attrs {
 	attrs := make(Attrs, len(e.Attrs)+1)
@@ -140,19 +218,18 @@ func (e *Entry) AttrsWithLoc() Attrs {
 
 // Entries are marshalled in a way that a simple string sort of them will
 // give the same result like we sort them in [# index-entry-sorting #].
-func (e *Entry) marshal() string {
+func (e *Entry) marshal() string { return e.format(e.AttrsWithNoLint()) }
+
+func (e *Entry) format(attrs Attrs) string {
 	b := &strings.Builder{}
 	w := csv.NewWriter(b)
 	w.Comma = ' '
 
 	rs := []string{e.Name, e.Loc.String()}
 
-	attrs := make(map[string]string, len(e.Attrs))
 	ks := make([]string, 0, len(attrs))
 
-	for k, v := range e.Attrs {
-		attrs[k] = v
-
+	for k := range attrs {
 		if k != "scope" { // this is handled separately below.
 			ks = append(ks, k)
 		}
@@ -161,7 +238,7 @@ func (e *Entry) marshal() string {
 	sort.Strings(ks)
 
 	// Scope always first because it's important.
-	if scope := e.Attrs["scope"]; scope != "" {
+	if scope := attrs["scope"]; scope != "" {
 		ks = append([]string{"scope"}, ks...)
 	}
 
@@ -210,5 +287,7 @@ func (e *Entry) unmarshal(text string) error {
 		}
 	}
 
+	e.TakeNoLint()
+
 	return nil
 }
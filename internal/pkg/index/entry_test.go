@@ -0,0 +1,36 @@
+package index
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+)
+
+func TestEntryNoLint(t *testing.T) {
+	ent := &Entry{
+		Name:       "a",
+		Attrs:      Attrs{"owner": "x"},
+		Loc:        scanner.Loc{Path: "f", Line: 1, StartColumn: 1, EndColumn: 2},
+		NoLint:     []string{"r", "s"},
+		NoLintFile: []string{"t"},
+	}
+
+	if got, exp := ent.String(), "a f:1.1-2 owner=x"; got != exp {
+		t.Errorf("string: %q != %q", got, exp)
+	}
+
+	text := ent.marshal()
+	if exp := `a f:1.1-2 nolint=r,s nolint-file=t owner=x`; text != exp {
+		t.Errorf("marshal: %q != %q", text, exp)
+	}
+
+	var got Entry
+	if err := got.unmarshal(text); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(&got, ent) {
+		t.Errorf("unmarshal: %+v != %+v", got, ent)
+	}
+}
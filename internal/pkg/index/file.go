@@ -0,0 +1,63 @@
+package index
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// File records the state of a scanned file at the time the index was built.
+// It allows to update an index incrementally, rescanning only files that have
+// changed since.
+type File struct {
+	Path    string
+	Size    int64
+	ModTime int64  // unix nanoseconds. 0 if unknown.
+	Hash    string // sha256 of content, hex encoded.
+}
+
+const filePrefix = "#file "
+
+// configPrefix marks the line, right after the version marker, recording the
+// fingerprint of the scanner config. It sorts before files.
+const configPrefix = "#config "
+
+// Files are marshalled with a prefix that sorts them after the version marker
+// but before all entries, keeping [# index-entry-sorting #] intact.
+func (f *File) marshal() string {
+	b := &strings.Builder{}
+	w := csv.NewWriter(b)
+	w.Comma = ' '
+
+	_ = w.Write([]string{f.Path, strconv.FormatInt(f.Size, 10), strconv.FormatInt(f.ModTime, 10), f.Hash})
+	w.Flush()
+
+	return filePrefix + strings.TrimSuffix(b.String(), "\n")
+}
+
+func (f *File) unmarshal(text string) error {
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(text, filePrefix)))
+	r.Comma = ' '
+
+	fs, err := r.Read()
+	if err != nil {
+		return err
+	}
+
+	if len(fs) != 4 {
+		return fmt.Errorf("invalid file record")
+	}
+
+	f.Path, f.Hash = fs[0], fs[3]
+
+	if f.Size, err = strconv.ParseInt(fs[1], 10, 64); err != nil {
+		return fmt.Errorf("invalid size: %w", err)
+	}
+
+	if f.ModTime, err = strconv.ParseInt(fs[2], 10, 64); err != nil {
+		return fmt.Errorf("invalid mod time: %w", err)
+	}
+
+	return nil
+}
@@ -21,8 +21,12 @@ func ReadFile(path string) (*Index, error) {
 
 	scanner := bufio.NewScanner(f)
 
-	first := true
-	ents := make([]*Entry, 0, 10)
+	var (
+		first  = true
+		ents   = make([]*Entry, 0, 10)
+		files  []*File
+		config string
+	)
 
 	for i := 1; scanner.Scan(); i++ {
 		text := strings.TrimSpace(scanner.Text())
@@ -31,7 +35,7 @@ func ReadFile(path string) (*Index, error) {
 		}
 
 		if first {
-			if !strings.HasPrefix(text, versionMarker+" ") {
+			if !isCompatibleVersion(text) {
 				return nil, fmt.Errorf("missing or incompatible index version marker - please reindex")
 			}
 
@@ -40,6 +44,23 @@ func ReadFile(path string) (*Index, error) {
 			continue
 		}
 
+		if strings.HasPrefix(text, configPrefix) {
+			config = strings.TrimPrefix(text, configPrefix)
+
+			continue
+		}
+
+		if strings.HasPrefix(text, filePrefix) {
+			file := &File{}
+			if err := file.unmarshal(text); err != nil {
+				return nil, fmt.Errorf("index line %d: %w", i, err)
+			}
+
+			files = append(files, file)
+
+			continue
+		}
+
 		ent := &Entry{}
 		if err := ent.unmarshal(text); err != nil {
 			return nil, fmt.Errorf("index line %d: %w", i, err)
@@ -48,7 +69,23 @@ func ReadFile(path string) (*Index, error) {
 		ents = append(ents, ent)
 	}
 
-	return NewIndex(ents), nil
+	idx := NewIndex(ents).SetConfig(config)
+
+	if files != nil {
+		idx.SetFiles(files)
+	}
+
+	return idx, nil
+}
+
+func isCompatibleVersion(text string) bool {
+	for _, m := range append([]string{versionMarker}, compatibleVersionMarkers...) {
+		if strings.HasPrefix(text, m+" ") {
+			return true
+		}
+	}
+
+	return false
 }
 
 var ErrStop = fmt.Errorf("stop")
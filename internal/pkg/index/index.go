@@ -8,9 +8,16 @@ import (
 	"sort"
 )
 
-const versionMarker = "# v4"
+const versionMarker = "# v5"
 
-type Index struct{ entries []*Entry }
+// Older versions that can still be read.
+var compatibleVersionMarkers = []string{"# v4"}
+
+type Index struct {
+	entries []*Entry
+	files   map[string]*File // nil if not tracked.
+	config  string           // fingerprint of the scanner config, if known.
+}
 
 // [# index-entry-sorting #] sorts by name, then loc.
 
@@ -41,6 +48,42 @@ func (i *Index) Size() int { return len(i.entries) }
 
 func (i *Index) Slice() []*Entry { return i.entries[:] }
 
+// SetFiles replaces the file records of i. It modifies i.
+func (i *Index) SetFiles(files []*File) *Index {
+	i.files = make(map[string]*File, len(files))
+	for _, f := range files {
+		i.files[f.Path] = f
+	}
+
+	return i
+}
+
+// SetConfig records the fingerprint of the scanner config the index was
+// built with. It modifies i.
+func (i *Index) SetConfig(fingerprint string) *Index {
+	i.config = fingerprint
+	return i
+}
+
+// Config returns the fingerprint of the scanner config the index was built
+// with, or "" if it is unknown.
+func (i *Index) Config() string { return i.config }
+
+// File returns the record of the file at path, or nil if there is none.
+func (i *Index) File(path string) *File { return i.files[path] }
+
+// Files returns all file records, sorted by path.
+func (i *Index) Files() []*File {
+	files := make([]*File, 0, len(i.files))
+	for _, f := range i.files {
+		files = append(files, f)
+	}
+
+	sort.Slice(files, func(a, b int) bool { return files[a].Path < files[b].Path })
+
+	return files
+}
+
 func WriteEntries(w io.Writer, index *Index) error {
 	for _, i := range index.entries {
 		text := i.marshal() + "\n"
@@ -93,6 +136,18 @@ func WriteFile(path string, index *Index, comment string) error {
 
 	fmt.Fprintf(f, "%s %s\n", versionMarker, comment)
 
+	if index.config != "" {
+		fmt.Fprintf(f, "%s%s\n", configPrefix, index.config)
+	}
+
+	for _, file := range index.Files() {
+		if _, err := fmt.Fprintln(f, file.marshal()); err != nil {
+			done()
+
+			return fmt.Errorf("write: %w", err)
+		}
+	}
+
 	if err := WriteEntries(f, index); err != nil {
 		done()
 
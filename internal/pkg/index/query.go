@@ -0,0 +1,305 @@
+package index
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cluttercode/clutter/pkg/strmatcher"
+)
+
+// Queries are boolean expressions over entries, for example:
+//
+//   name~"^api-" and (lang=go or lang=py) and not deprecated
+//
+// Grammar:
+//
+//   query   = or
+//   or      = and { "or" and }
+//   and     = unary { [ "and" ] unary }
+//   unary   = "not" unary | primary
+//   primary = "(" or ")" | term
+//   term    = field op value | "has:" field | field
+//   op      = "=" (exact) | "~" (regexp) | "%" (glob)
+//
+// field is either "name", for the tag name, "loc" for the tag location, or
+// an attribute name. A field by itself is true if the entry has that
+// attribute. Values may be quoted using Go string syntax, and must be quoted
+// if they contain spaces, parentheses, operators or quotes.
+
+type queryTokenKind int
+
+const (
+	tokEOF queryTokenKind = iota
+	tokLParen
+	tokRParen
+	tokOp
+	tokWord
+	tokString
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	text string
+	pos  int
+}
+
+const querySpecials = "()=~%\"`"
+
+func lexQuery(text string) ([]queryToken, error) {
+	var toks []queryToken
+
+	for i := 0; i < len(text); {
+		c := text[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '(':
+			toks = append(toks, queryToken{kind: tokLParen, text: "(", pos: i})
+			i++
+
+		case c == ')':
+			toks = append(toks, queryToken{kind: tokRParen, text: ")", pos: i})
+			i++
+
+		case c == '=' || c == '~' || c == '%':
+			toks = append(toks, queryToken{kind: tokOp, text: text[i : i+1], pos: i})
+			i++
+
+		case c == '"' || c == '`':
+			end := i + 1
+
+			for ; end < len(text) && text[end] != c; end++ {
+				if c == '"' && text[end] == '\\' {
+					end++
+				}
+			}
+
+			if end >= len(text) {
+				return nil, fmt.Errorf("column %d: unterminated string", i+1)
+			}
+
+			s, err := strconv.Unquote(text[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("column %d: invalid string: %w", i+1, err)
+			}
+
+			toks = append(toks, queryToken{kind: tokString, text: s, pos: i})
+
+			i = end + 1
+
+		default:
+			end := i
+			for ; end < len(text) && !strings.ContainsRune(querySpecials+" \t\n\r", rune(text[end])); end++ {
+			}
+
+			toks = append(toks, queryToken{kind: tokWord, text: text[i:end], pos: i})
+
+			i = end
+		}
+	}
+
+	return append(toks, queryToken{kind: tokEOF, pos: len(text)}), nil
+}
+
+type queryParser struct {
+	toks []queryToken
+	i    int
+}
+
+func (p *queryParser) peek() queryToken { return p.toks[p.i] }
+
+func (p *queryParser) next() queryToken {
+	t := p.toks[p.i]
+
+	if t.kind != tokEOF {
+		p.i++
+	}
+
+	return t
+}
+
+func (p *queryParser) isKeyword(t queryToken, kw string) bool {
+	return t.kind == tokWord && t.text == kw
+}
+
+func (p *queryParser) or() (func(*Entry) bool, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword(p.peek(), "or") {
+		p.next()
+
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		l = func(l, r func(*Entry) bool) func(*Entry) bool {
+			return func(e *Entry) bool { return l(e) || r(e) }
+		}(l, r)
+	}
+
+	return l, nil
+}
+
+func (p *queryParser) and() (func(*Entry) bool, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+
+		if p.isKeyword(t, "and") {
+			p.next()
+		} else if t.kind == tokEOF || t.kind == tokRParen || p.isKeyword(t, "or") {
+			return l, nil
+		}
+
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		l = func(l, r func(*Entry) bool) func(*Entry) bool {
+			return func(e *Entry) bool { return l(e) && r(e) }
+		}(l, r)
+	}
+}
+
+func (p *queryParser) unary() (func(*Entry) bool, error) {
+	if p.isKeyword(p.peek(), "not") {
+		p.next()
+
+		m, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return func(e *Entry) bool { return !m(e) }, nil
+	}
+
+	return p.primary()
+}
+
+func (p *queryParser) primary() (func(*Entry) bool, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokLParen:
+		m, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if t := p.next(); t.kind != tokRParen {
+			return nil, fmt.Errorf("column %d: expected \")\"", t.pos+1)
+		}
+
+		return m, nil
+
+	case tokWord, tokString:
+		return p.term(t)
+
+	case tokEOF:
+		return nil, fmt.Errorf("column %d: unexpected end of query", t.pos+1)
+	}
+
+	return nil, fmt.Errorf("column %d: unexpected %q", t.pos+1, t.text)
+}
+
+func (p *queryParser) term(field queryToken) (func(*Entry) bool, error) {
+	if field.kind == tokWord && (field.text == "and" || field.text == "or") {
+		return nil, fmt.Errorf("column %d: unexpected %q", field.pos+1, field.text)
+	}
+
+	if op := p.peek(); op.kind == tokOp {
+		p.next()
+
+		v := p.next()
+		if v.kind != tokWord && v.kind != tokString {
+			return nil, fmt.Errorf("column %d: expected value", v.pos+1)
+		}
+
+		var compile strmatcher.Compiler
+
+		switch op.text {
+		case "=":
+			compile = strmatcher.CompileExactMatcher
+		case "~":
+			compile = strmatcher.CompileRegexpMatcher
+		case "%":
+			compile = strmatcher.CompileGlobMatcher
+		}
+
+		m, err := compile(v.text)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: invalid pattern: %w", v.pos+1, err)
+		}
+
+		k := field.text
+
+		return func(e *Entry) bool {
+			v, ok := e.field(k)
+			return ok && m(v)
+		}, nil
+	}
+
+	k := field.text
+	if field.kind == tokWord {
+		k = strings.TrimPrefix(k, "has:")
+	}
+
+	return func(e *Entry) bool {
+		_, ok := e.field(k)
+		return ok
+	}, nil
+}
+
+// field returns the value of a query field of e. See AttrsWithLoc.
+func (e *Entry) field(k string) (string, bool) {
+	switch k {
+	case "name":
+		return e.Name, e.Name != ""
+	case "loc":
+		return e.Loc.String(), true
+	}
+
+	v, ok := e.Attrs[k]
+
+	return v, ok
+}
+
+// CompileQuery compiles a query expression into a matcher. Like matchers
+// returned by Entry.Matcher, the matcher never matches search entries.
+func CompileQuery(text string) (func(*Entry) bool, error) {
+	toks, err := lexQuery(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := queryParser{toks: toks}
+
+	m, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("column %d: unexpected %q", t.pos+1, t.text)
+	}
+
+	return func(e *Entry) bool {
+		if _, search := e.IsSearch(); search {
+			return false
+		}
+
+		return m(e)
+	}, nil
+}
@@ -0,0 +1,81 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+)
+
+func TestCompileQuery(t *testing.T) {
+	ents := []*Entry{
+		{Name: "api-users", Attrs: Attrs{"lang": "go", "owner": "zumi"}, Loc: scanner.Loc{Path: "a/x.go"}},
+		{Name: "api-old", Attrs: Attrs{"lang": "py", "deprecated": ""}, Loc: scanner.Loc{Path: "a/y.py"}},
+		{Name: "web", Attrs: Attrs{"lang": "go"}, Loc: scanner.Loc{Path: "b/z.go"}},
+		{Name: "api-search", Attrs: Attrs{"search": "glob"}, Loc: scanner.Loc{Path: "a/x.go"}},
+	}
+
+	tests := []struct {
+		query string
+		exp   []string // names of matching entries.
+		err   bool
+	}{
+		{query: "name=web", exp: []string{"web"}},
+		{query: `name~"^api-"`, exp: []string{"api-users", "api-old"}},
+		{query: "name%api-*", exp: []string{"api-users", "api-old"}},
+		{query: `name~"^api-" and (lang=go or lang=py) and not deprecated`, exp: []string{"api-users"}},
+		{query: "lang=go owner=zumi", exp: []string{"api-users"}},
+		{query: "has:owner or deprecated", exp: []string{"api-users", "api-old"}},
+		{query: "not has:owner", exp: []string{"api-old", "web"}},
+		{query: "lang=go and not (name=web)", exp: []string{"api-users"}},
+		{query: "lang=go or lang=py and deprecated", exp: []string{"api-users", "api-old", "web"}},
+		{query: "(lang=go or lang=py) and deprecated", exp: []string{"api-old"}},
+		{query: "not not deprecated", exp: []string{"api-old"}},
+		{query: `loc%"b/*"`, exp: []string{"web"}},
+		{query: `deprecated=""`, exp: []string{"api-old"}},
+		{query: "no-such-attr"},
+		{query: "", err: true},
+		{query: "(lang=go", err: true},
+		{query: "lang=go)", err: true},
+		{query: "lang=", err: true},
+		{query: "and lang=go", err: true},
+		{query: "lang=go or", err: true},
+		{query: `name~"["`, err: true},
+		{query: `name="unterminated`, err: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			m, err := CompileQuery(test.query)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("error expected, but got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			var names []string
+
+			for _, ent := range ents {
+				if m(ent) {
+					names = append(names, ent.Name)
+				}
+			}
+
+			if len(names) != len(test.exp) {
+				t.Fatalf("%v != %v", names, test.exp)
+			}
+
+			for i := range names {
+				if names[i] != test.exp[i] {
+					t.Fatalf("%v != %v", names, test.exp)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+// Package indexer builds indices from source trees, reusing the entries of a
+// previous index for files that did not change since it was built. See
+// [# incremental-index #].
+package indexer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/parser"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+// Files modified less than this long before they are scanned are not
+// trusted by their modification time in the next update, since they might
+// be modified again within the file system timestamp granularity.
+const racyWindow = 2 * time.Second
+
+type Stats struct {
+	Scanned   int // files that were (re)scanned.
+	Unchanged int // files whose entries were reused.
+	Removed   int // files that no longer exist.
+}
+
+// Update scans the tree at root and returns a new index for it. Entries of
+// prev are reused for files whose size and modification time (or, failing
+// that, content hash) did not change. prev may be nil, in which case all
+// files are scanned, as they are if prev was built with a different scanner
+// config. The returned index records the config and the state of all files,
+// to be used by the next update.
+func Update(z *zlog.Logger, cfg scanner.Config, root string, prev *index.Index) (*index.Index, *Stats, error) {
+	list, err := scanner.NewLister(z, cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("lister: %w", err)
+	}
+
+	paths, err := list(root)
+
+	var errs scanner.FileErrors
+	if err != nil && !errors.As(err, &errs) {
+		return nil, nil, fmt.Errorf("list: %w", err)
+	}
+
+	config := cfg.Fingerprint()
+
+	if prev == nil {
+		prev = index.NewIndex(nil)
+	} else if prev.Config() != config {
+		z.Infow("scanner config changed, rescanning all files", "prev", prev.Config(), "curr", config)
+
+		prev = index.NewIndex(nil)
+	}
+
+	prevEnts := make(map[string][]*index.Entry)
+	for _, ent := range prev.Slice() {
+		prevEnts[ent.Loc.Path] = append(prevEnts[ent.Loc.Path], ent)
+	}
+
+	var (
+		stats   Stats
+		now     = time.Now()
+		ents    []*index.Entry
+		files   []*index.File
+		dirty   []string
+		present = make(map[string]bool, len(paths))
+	)
+
+	for _, path := range paths {
+		present[path] = true
+
+		fi, err := os.Stat(path)
+		if err != nil {
+			errs = append(errs, &scanner.FileError{Path: path, Err: err})
+			continue
+		}
+
+		if old := prev.File(path); old != nil && old.ModTime != 0 && old.Size == fi.Size() && old.ModTime == fi.ModTime().UnixNano() {
+			z.Debugw("unchanged", "path", path)
+
+			stats.Unchanged++
+
+			ents = append(ents, prevEnts[path]...)
+			files = append(files, old)
+
+			continue
+		}
+
+		dirty = append(dirty, path)
+	}
+
+	for _, f := range prev.Files() {
+		if !present[f.Path] {
+			z.Debugw("removed", "path", f.Path)
+			stats.Removed++
+		}
+	}
+
+	var (
+		l      sync.Mutex
+		reused = make(map[string]bool)
+	)
+
+	// files are read entirely once, for both hashing and scanning.
+	scanFile := func(z *zlog.Logger, path string, f func(*scanner.RawElement) error) error {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		bs, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(bs)
+
+		file := &index.File{
+			Path:    path,
+			Size:    int64(len(bs)),
+			ModTime: fi.ModTime().UnixNano(),
+			Hash:    hex.EncodeToString(sum[:]),
+		}
+
+		if now.Sub(fi.ModTime()) < racyWindow {
+			file.ModTime = 0 // next update will check the hash.
+		}
+
+		old := prev.File(path)
+		same := old != nil && old.Hash == file.Hash
+
+		l.Lock()
+		files = append(files, file)
+		reused[path] = same
+		l.Unlock()
+
+		if same {
+			z.Debug("touched, but content is unchanged")
+			return nil
+		}
+
+		z.Debug("scanning")
+
+		return scanner.ScanReader(z, cfg, path, bytes.NewReader(bs), f)
+	}
+
+	elems, err := scanner.ScanPaths(z, cfg, dirty, scanFile, nil)
+	if err != nil {
+		var scanErrs scanner.FileErrors
+		if !errors.As(err, &scanErrs) {
+			return nil, nil, fmt.Errorf("scan: %w", err)
+		}
+
+		errs = append(errs, scanErrs...)
+	}
+
+	for _, path := range dirty {
+		if reused[path] {
+			stats.Unchanged++
+			ents = append(ents, prevEnts[path]...)
+		} else {
+			stats.Scanned++
+		}
+	}
+
+	newEnts, err := parser.ParseElements(elems)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parser: %w", err)
+	}
+
+	if len(errs) != 0 {
+		return nil, nil, errs
+	}
+
+	return index.NewIndex(append(ents, newEnts...)).SetFiles(files).SetConfig(config), &stats, nil
+}
@@ -0,0 +1,88 @@
+// [# %stop! #]
+
+package indexer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestUpdate(t *testing.T) {
+	root, err := ioutil.TempDir("", "clutter-indexer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	old := time.Now().Add(-time.Hour)
+
+	write := func(name, text string, mtime time.Time) {
+		path := filepath.Join(root, name)
+
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := scanner.Config{Bracket: scanner.BracketConfig{Left: "[#", Right: "#]"}}
+
+	update := func(prev *index.Index, exp Stats, expNames ...string) *index.Index {
+		idx, stats, err := Update(zlog.NewNopLogger(), cfg, root, prev)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if *stats != exp {
+			t.Errorf("stats: %+v != %+v", *stats, exp)
+		}
+
+		var names []string
+		for _, ent := range idx.Slice() {
+			names = append(names, ent.Name)
+		}
+
+		if !reflect.DeepEqual(expNames, names) {
+			t.Errorf("names: %v != %v", expNames, names)
+		}
+
+		return idx
+	}
+
+	write("a", "[# a #]", old)
+	write("b", "[# b #]", old)
+	write("c", "[# c #]", old)
+
+	idx := update(nil, Stats{Scanned: 3}, "a", "b", "c")
+	idx = update(idx, Stats{Unchanged: 3}, "a", "b", "c")
+
+	write("b", "[# b #]", old.Add(time.Minute)) // touched only.
+	write("c", "[# d #]", old.Add(time.Minute))
+	os.Remove(filepath.Join(root, "a"))
+
+	idx = update(idx, Stats{Scanned: 1, Unchanged: 1, Removed: 1}, "b", "d")
+
+	// racy: modified in the same second it was last scanned.
+	write("b", "[# e #]", time.Now())
+	idx = update(idx, Stats{Scanned: 1, Unchanged: 1}, "d", "e")
+
+	write("b", "[# f #]", time.Now())
+	idx = update(idx, Stats{Scanned: 1, Unchanged: 1}, "d", "f")
+
+	// a different scanner config might find different tags in all files.
+	cfg.Mode = scanner.ModeComments
+	update(idx, Stats{Scanned: 2}, "d", "f")
+}
@@ -0,0 +1,146 @@
+package linter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+
+	"github.com/cluttercode/clutter/pkg/strmatcher"
+)
+
+type check func(context.Context, *index.Entry) (bool, error)
+
+// builtinChecks compiles the built-in checks of r. Checks on names are not
+// applied to search tags, as their names are patterns.
+func builtinChecks(r Rule) ([]check, error) {
+	var checks []check
+
+	if len(r.RequiredAttrs) > 0 {
+		required := r.RequiredAttrs
+
+		checks = append(checks, func(_ context.Context, ent *index.Entry) (bool, error) {
+			for _, k := range required {
+				if _, ok := ent.Attrs[k]; !ok {
+					return false, nil
+				}
+			}
+
+			return true, nil
+		})
+	}
+
+	if len(r.AllowedAttrs) > 0 {
+		// special attributes are always allowed.
+		allowed := make(map[string]bool, len(r.AllowedAttrs))
+		for _, k := range r.AllowedAttrs {
+			allowed[k] = true
+		}
+
+		checks = append(checks, func(_ context.Context, ent *index.Entry) (bool, error) {
+			for k := range ent.Attrs {
+				if !allowed[k] && !index.IsSpecialAttr(k) {
+					return false, nil
+				}
+			}
+
+			return true, nil
+		})
+	}
+
+	for _, k := range sortedKeys(r.AttrRegexps) {
+		re, err := regexp.Compile(r.AttrRegexps[k])
+		if err != nil {
+			return nil, fmt.Errorf("attr-re %q: %w", k, err)
+		}
+
+		checks = append(checks, attrCheck(k, re.MatchString))
+	}
+
+	for _, k := range sortedEnumKeys(r.AttrEnums) {
+		checks = append(checks, attrCheck(k, strmatcher.NewExactMatchers(r.AttrEnums[k]).Any))
+	}
+
+	if r.NameRegexp != "" {
+		re, err := regexp.Compile(r.NameRegexp)
+		if err != nil {
+			return nil, fmt.Errorf("name-re: %w", err)
+		}
+
+		checks = append(checks, nameCheck(re.MatchString))
+	}
+
+	if len(r.ForbiddenNames) > 0 {
+		forbidden := strmatcher.NewExactMatchers(r.ForbiddenNames).Any
+
+		checks = append(checks, nameCheck(func(name string) bool { return !forbidden(name) }))
+	}
+
+	if len(r.NamePaths) > 0 {
+		paths := make(map[string]strmatcher.Matchers, len(r.NamePaths))
+
+		for name, globs := range r.NamePaths {
+			ms, err := strmatcher.NewGlobMatchers(globs)
+			if err != nil {
+				return nil, fmt.Errorf("name-paths %q: %w", name, err)
+			}
+
+			paths[name] = ms
+		}
+
+		checks = append(checks, func(_ context.Context, ent *index.Entry) (bool, error) {
+			if _, search := ent.IsSearch(); search {
+				return true, nil
+			}
+
+			ms, ok := paths[ent.Name]
+
+			return !ok || ms.Any(ent.Loc.Path), nil
+		})
+	}
+
+	return checks, nil
+}
+
+// attrCheck passes if the entry does not have the attribute k, or if its
+// value satisfies ok.
+func attrCheck(k string, ok func(string) bool) check {
+	return func(_ context.Context, ent *index.Entry) (bool, error) {
+		v, has := ent.Attrs[k]
+		return !has || ok(v), nil
+	}
+}
+
+func nameCheck(ok func(string) bool) check {
+	return func(_ context.Context, ent *index.Entry) (bool, error) {
+		if _, search := ent.IsSearch(); search {
+			return true, nil
+		}
+
+		return ok(ent.Name), nil
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func sortedEnumKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,163 @@
+package linter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestBuiltinRules(t *testing.T) {
+	ent := func(name, path string, attrs index.Attrs) *index.Entry {
+		return &index.Entry{Name: name, Attrs: attrs, Loc: scanner.Loc{Path: path}}
+	}
+
+	tests := []struct {
+		name string
+		rule Rule
+		ent  *index.Entry
+		pass bool
+		err  bool
+	}{
+		{
+			name: "required present",
+			rule: Rule{RequiredAttrs: []string{"owner"}},
+			ent:  ent("x", "a", index.Attrs{"owner": "zumi"}),
+			pass: true,
+		},
+		{
+			name: "required missing",
+			rule: Rule{RequiredAttrs: []string{"owner", "lang"}},
+			ent:  ent("x", "a", index.Attrs{"owner": "zumi"}),
+		},
+		{
+			name: "allowed",
+			rule: Rule{AllowedAttrs: []string{"owner"}},
+			ent:  ent("x", "a", index.Attrs{"owner": "zumi", "scope": "a"}),
+			pass: true,
+		},
+		{
+			name: "not allowed",
+			rule: Rule{AllowedAttrs: []string{"owner"}},
+			ent:  ent("x", "a", index.Attrs{"lang": "go"}),
+		},
+		{
+			name: "attr re match",
+			rule: Rule{AttrRegexps: map[string]string{"lang": "^(go|py)$"}},
+			ent:  ent("x", "a", index.Attrs{"lang": "go"}),
+			pass: true,
+		},
+		{
+			name: "attr re absent",
+			rule: Rule{AttrRegexps: map[string]string{"lang": "^(go|py)$"}},
+			ent:  ent("x", "a", nil),
+			pass: true,
+		},
+		{
+			name: "attr re mismatch",
+			rule: Rule{AttrRegexps: map[string]string{"lang": "^(go|py)$"}},
+			ent:  ent("x", "a", index.Attrs{"lang": "hs"}),
+		},
+		{
+			name: "attr re invalid",
+			rule: Rule{AttrRegexps: map[string]string{"lang": "("}},
+			err:  true,
+		},
+		{
+			name: "attr enum",
+			rule: Rule{AttrEnums: map[string][]string{"lang": {"go", "py"}}},
+			ent:  ent("x", "a", index.Attrs{"lang": "py"}),
+			pass: true,
+		},
+		{
+			name: "attr enum mismatch",
+			rule: Rule{AttrEnums: map[string][]string{"lang": {"go", "py"}}},
+			ent:  ent("x", "a", index.Attrs{"lang": "pyy"}),
+		},
+		{
+			name: "name re",
+			rule: Rule{NameRegexp: "^[a-z-]+$"},
+			ent:  ent("api-x", "a", nil),
+			pass: true,
+		},
+		{
+			name: "name re mismatch",
+			rule: Rule{NameRegexp: "^[a-z-]+$"},
+			ent:  ent("API", "a", nil),
+		},
+		{
+			name: "name re ignores search tags",
+			rule: Rule{NameRegexp: "^[a-z-]+$"},
+			ent:  ent("API*", "a", index.Attrs{"search": "glob"}),
+			pass: true,
+		},
+		{
+			name: "forbidden name",
+			rule: Rule{ForbiddenNames: []string{"todo"}},
+			ent:  ent("todo", "a", nil),
+		},
+		{
+			name: "name in allowed path",
+			rule: Rule{NamePaths: map[string][]string{"api": {"api/*"}}},
+			ent:  ent("api", "api/x.go", nil),
+			pass: true,
+		},
+		{
+			name: "name outside allowed path",
+			rule: Rule{NamePaths: map[string][]string{"api": {"api/*"}}},
+			ent:  ent("api", "web/x.go", nil),
+		},
+		{
+			name: "other name anywhere",
+			rule: Rule{NamePaths: map[string][]string{"api": {"api/*"}}},
+			ent:  ent("web", "web/x.go", nil),
+			pass: true,
+		},
+		{
+			name: "path gating",
+			rule: Rule{PathGlob: "api/*", ForbiddenNames: []string{"todo"}},
+			ent:  ent("todo", "web/x.go", nil),
+			pass: true,
+		},
+		{
+			name: "builtin and shell",
+			rule: Rule{RequiredAttrs: []string{"owner"}, Shell: []string{"false"}},
+			ent:  ent("x", "a", index.Attrs{"owner": "zumi"}),
+		},
+		{
+			name: "no checks",
+			rule: Rule{Name: "empty"},
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := NewLinter(zlog.NewNopLogger(), Config{Rules: []Rule{test.rule}})
+
+			if test.err {
+				if err == nil {
+					t.Errorf("error expected, but got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			pass, err := l.LintRule(context.Background(), 0, test.ent)
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			if pass != test.pass {
+				t.Errorf("pass: %v != %v", pass, test.pass)
+			}
+		})
+	}
+}
@@ -1,10 +1,105 @@
 package linter
 
+import (
+	"fmt"
+	"time"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+
+	// SeverityNone is above all severities. It is not a valid rule severity.
+	SeverityNone Severity = "none"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityInfo:    1,
+	SeverityWarning: 2,
+	SeverityError:   3,
+	SeverityNone:    4,
+}
+
+func ParseSeverity(text string) (Severity, error) {
+	s := Severity(text)
+	if _, ok := severityRanks[s]; !ok {
+		return "", fmt.Errorf("invalid severity %q", text)
+	}
+
+	return s, nil
+}
+
+// AtLeast returns true if s is as severe as other, or more.
+func (s Severity) AtLeast(other Severity) bool { return severityRanks[s] >= severityRanks[other] }
+
+// Rule is evaluated for every entry whose path matches PathGlob or
+// PathRegexp, if given. An entry violates the rule if any of the rule's
+// checks fails. At least one check must be specified.
 type Rule struct {
-	Name       string   `yaml:"name"`
-	PathGlob   string   `yaml:"path-glob"`
-	PathRegexp string   `yaml:"path-re"`
-	Shell      []string `yaml:"shell"`
+	Name        string   `yaml:"name"`
+	Severity    Severity `yaml:"severity"` // error (default), warning or info.
+	Description string   `yaml:"description"`
+
+	// Timeout bounds the time it takes to evaluate the rule for a single
+	// entry. A rule that times out is reported as such, rather than as a
+	// violation. No timeout if zero.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Message is a text/template describing a violation. Its data is the
+	// violating entry, see messageData.
+	Message string `yaml:"message"`
+
+	PathGlob   string `yaml:"path-glob"`
+	PathRegexp string `yaml:"path-re"`
+
+	// Built-in checks, evaluated in process.
+
+	RequiredAttrs  []string            `yaml:"required-attrs"`  // attributes that must be present.
+	AllowedAttrs   []string            `yaml:"allowed-attrs"`   // if given, only these attributes (and scope and search) may be present.
+	AttrRegexps    map[string]string   `yaml:"attr-re"`         // attribute -> regexp its value must match, if present.
+	AttrEnums      map[string][]string `yaml:"attr-enum"`       // attribute -> allowed values, if present.
+	NameRegexp     string              `yaml:"name-re"`         // regexp names must match.
+	ForbiddenNames []string            `yaml:"forbidden-names"` // names that must not be used.
+	NamePaths      map[string][]string `yaml:"name-paths"`      // name -> path globs the name may only appear in.
+
+	// Index checks, evaluated against all tags in the index. Tags with the
+	// same name are in the same scope if either refers to the other.
+
+	MinCount        int  `yaml:"min-count"`         // names must appear at least this many times.
+	NoOrphans       bool `yaml:"no-orphans"`        // tags must have another tag with the same name in scope.
+	UniqueInScope   bool `yaml:"unique-in-scope"`   // tags must not have another tag with the same name in scope.
+	SearchMustMatch bool `yaml:"search-must-match"` // search tags must match at least one tag.
+
+	// Shell is a command that is run for each entry, with the entry in its
+	// environment, see entVars, and as JSON on its stdin, see shellEntry. The
+	// check fails if the command exits with a non-zero status.
+	Shell []string `yaml:"shell"`
+
+	// Batch runs Shell once for all entries instead, see shellBatch.
+	Batch bool `yaml:"batch"`
+
+	Source  bool `yaml:"source"`  // pass the source lines of the tag to Shell.
+	Context int  `yaml:"context"` // pass this many lines around the tag to Shell. Implies Source.
+
+	// Fix proposes replacements for tags that violate the rule.
+	Fix *Fix `yaml:"fix"`
+}
+
+// Fix describes how to fix a tag that violates a rule. Built-in fixes are
+// applied in the order of the fields below. Shell cannot be combined with
+// them.
+type Fix struct {
+	Rename     map[string]string `yaml:"rename"`      // old name -> new name.
+	SetAttrs   map[string]string `yaml:"set-attrs"`   // attribute -> value to add it with, if missing.
+	LowerAttrs []string          `yaml:"lower-attrs"` // attributes whose values are lower-cased.
+
+	// Shell takes the replacement tag text, without brackets, from the
+	// stdout of the rule's shell command when it fails. For batch rules, the
+	// text follows "fail" on the same line.
+	Shell bool `yaml:"shell"`
 }
 
 type Config struct {
@@ -0,0 +1,126 @@
+package linter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/parser"
+)
+
+// newFix returns a function that returns the replacement text of an entry,
+// or an empty string if there is none. It returns nil if the rule has no fix.
+func (l *Linter) newFix(r *Rule) (func(context.Context, *index.Entry) (string, error), error) {
+	fix := r.Fix
+	if fix == nil {
+		return nil, nil
+	}
+
+	if fix.Shell {
+		if len(r.Shell) == 0 {
+			return nil, fmt.Errorf("shell requires a shell check")
+		}
+
+		if len(fix.Rename) > 0 || len(fix.SetAttrs) > 0 || len(fix.LowerAttrs) > 0 {
+			return nil, fmt.Errorf("shell cannot be combined with other fixes")
+		}
+
+		if r.Batch {
+			return func(ctx context.Context, ent *index.Entry) (string, error) {
+				answers, err := l.runBatch(ctx, r, []*index.Entry{ent})
+				if err != nil {
+					return "", err
+				}
+
+				return answers[0].fix, nil
+			}, nil
+		}
+
+		return func(ctx context.Context, ent *index.Entry) (string, error) {
+			pass, out, err := l.runShell(ctx, r, ent)
+			if err != nil || pass {
+				return "", err
+			}
+
+			return strings.TrimSpace(string(out)), nil
+		}, nil
+	}
+
+	for from, to := range fix.Rename {
+		if !parser.IsValidName(to) {
+			return nil, fmt.Errorf("rename: %q: invalid name %q", from, to)
+		}
+	}
+
+	for k := range fix.SetAttrs {
+		if !parser.IsValidAttrName(k) {
+			return nil, fmt.Errorf("set-attrs: invalid attribute name %q", k)
+		}
+	}
+
+	if len(fix.Rename) == 0 && len(fix.SetAttrs) == 0 && len(fix.LowerAttrs) == 0 {
+		return nil, fmt.Errorf("no fixes specified")
+	}
+
+	return func(_ context.Context, ent *index.Entry) (string, error) {
+		fixed := *ent
+		fixed.Attrs = make(index.Attrs, len(ent.Attrs)+len(fix.SetAttrs))
+
+		for k, v := range ent.Attrs {
+			fixed.Attrs[k] = v
+		}
+
+		// names of search tags are patterns.
+		if _, search := ent.IsSearch(); !search {
+			if to, ok := fix.Rename[ent.Name]; ok {
+				fixed.Name = to
+			}
+		}
+
+		for k, v := range fix.SetAttrs {
+			if _, ok := fixed.Attrs[k]; !ok {
+				fixed.Attrs[k] = v
+			}
+		}
+
+		for _, k := range fix.LowerAttrs {
+			if v, ok := fixed.Attrs[k]; ok {
+				fixed.Attrs[k] = strings.ToLower(v)
+			}
+		}
+
+		text := parser.FormatElement(&fixed)
+		if text == parser.FormatElement(ent) {
+			return "", nil
+		}
+
+		return text, nil
+	}, nil
+}
+
+// Fix returns the replacement text, without brackets, that fixes the
+// violation of the ith rule by ent, or an empty string if there is none.
+// ent should have only the attributes written in the tag itself, as they are
+// all written back.
+func (l *Linter) Fix(ctx context.Context, i int, ent *index.Entry) (string, error) {
+	fix := l.rules[i].fix
+	if fix == nil {
+		return "", nil
+	}
+
+	ctx, cancel := l.withTimeout(ctx, i)
+	defer cancel()
+
+	text, err := fix(ctx, ent)
+
+	if err := l.timedOut(ctx, i); err != nil {
+		return "", err
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("fix: %w", err)
+	}
+
+	return text, nil
+}
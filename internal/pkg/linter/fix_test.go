@@ -0,0 +1,106 @@
+package linter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestFix(t *testing.T) {
+	ent := &index.Entry{
+		Name:  "old",
+		Attrs: index.Attrs{"lang": "Go", "scope": "dir/file"},
+		Loc:   scanner.Loc{Path: "dir/file", Line: 1, StartColumn: 1, EndColumn: 10},
+	}
+
+	tests := []struct {
+		name string
+		rule Rule
+		exp  string
+		err  bool
+	}{
+		{
+			name: "none",
+			rule: Rule{NameRegexp: "^new$"},
+		},
+		{
+			name: "rename",
+			rule: Rule{NameRegexp: "^new$", Fix: &Fix{Rename: map[string]string{"old": "new"}}},
+			exp:  ".new lang=Go",
+		},
+		{
+			name: "set and lower",
+			rule: Rule{RequiredAttrs: []string{"owner"}, Fix: &Fix{SetAttrs: map[string]string{"owner": "unknown", "lang": "py"}, LowerAttrs: []string{"lang"}}},
+			exp:  ".old lang=go owner=unknown",
+		},
+		{
+			name: "no change",
+			rule: Rule{NameRegexp: "^new$", Fix: &Fix{Rename: map[string]string{"other": "new"}}},
+		},
+		{
+			name: "shell",
+			rule: Rule{Shell: []string{"sh", "-c", `echo "$ENT_NAME fixed"; false`}, Fix: &Fix{Shell: true}},
+			exp:  "old fixed",
+		},
+		{
+			name: "shell pass",
+			rule: Rule{Shell: []string{"sh", "-c", `echo "$ENT_NAME fixed"`}, Fix: &Fix{Shell: true}},
+		},
+		{
+			name: "batch",
+			rule: Rule{Shell: []string{"sh", "-c", `read -r l; echo "fail  new "`}, Batch: true, Fix: &Fix{Shell: true}},
+			exp:  "new",
+		},
+		{
+			name: "shell without shell",
+			rule: Rule{NameRegexp: "^new$", Fix: &Fix{Shell: true}},
+			err:  true,
+		},
+		{
+			name: "shell and rename",
+			rule: Rule{Shell: []string{"false"}, Fix: &Fix{Shell: true, Rename: map[string]string{"old": "new"}}},
+			err:  true,
+		},
+		{
+			name: "invalid name",
+			rule: Rule{NameRegexp: "^new$", Fix: &Fix{Rename: map[string]string{"old": "not valid"}}},
+			err:  true,
+		},
+		{
+			name: "empty",
+			rule: Rule{NameRegexp: "^new$", Fix: &Fix{}},
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := NewLinter(zlog.NewNopLogger(), Config{Rules: []Rule{test.rule}})
+
+			if test.err {
+				if err == nil {
+					t.Errorf("error expected, but got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			text, err := l.Fix(context.Background(), 0, ent)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if text != test.exp {
+				t.Errorf("%q != %q", text, test.exp)
+			}
+		})
+	}
+}
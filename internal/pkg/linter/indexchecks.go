@@ -0,0 +1,111 @@
+package linter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+)
+
+// indexView is the whole index, as seen by index checks.
+type indexView struct {
+	idx    *index.Index
+	byName map[string][]*index.Entry // non-search entries only.
+}
+
+func newIndexView(idx *index.Index) *indexView {
+	v := &indexView{idx: idx, byName: make(map[string][]*index.Entry)}
+
+	_ = index.ForEach(idx, func(ent *index.Entry) error {
+		if _, search := ent.IsSearch(); !search {
+			v.byName[ent.Name] = append(v.byName[ent.Name], ent)
+		}
+
+		return nil
+	})
+
+	return v
+}
+
+// inScope returns the other entries with the same name as ent that are in its
+// scope, or that it is in the scope of.
+func (v *indexView) inScope(ent *index.Entry) []*index.Entry {
+	var ents []*index.Entry
+
+	for _, other := range v.byName[ent.Name] {
+		if other.Loc != ent.Loc && (other.IsReferredBy(ent) || ent.IsReferredBy(other)) {
+			ents = append(ents, other)
+		}
+	}
+
+	return ents
+}
+
+type indexCheck func(context.Context, *indexView, *index.Entry) (bool, error)
+
+// indexChecks compiles the checks of r that are evaluated against the whole
+// index. All but search-must-match apply only to non-search tags.
+func indexChecks(r Rule) ([]indexCheck, error) {
+	var checks []indexCheck
+
+	if r.MinCount < 0 {
+		return nil, fmt.Errorf("min-count: must not be negative")
+	}
+
+	if r.MinCount > 0 {
+		n := r.MinCount
+
+		checks = append(checks, nonSearchCheck(func(v *indexView, ent *index.Entry) bool {
+			return len(v.byName[ent.Name]) >= n
+		}))
+	}
+
+	if r.NoOrphans {
+		checks = append(checks, nonSearchCheck(func(v *indexView, ent *index.Entry) bool {
+			return len(v.inScope(ent)) > 0
+		}))
+	}
+
+	if r.UniqueInScope {
+		checks = append(checks, nonSearchCheck(func(v *indexView, ent *index.Entry) bool {
+			return len(v.inScope(ent)) == 0
+		}))
+	}
+
+	if r.SearchMustMatch {
+		checks = append(checks, func(_ context.Context, v *indexView, ent *index.Entry) (bool, error) {
+			if _, search := ent.IsSearch(); !search {
+				return true, nil
+			}
+
+			m, err := ent.Matcher()
+			if err != nil {
+				return false, fmt.Errorf("%v: %w", ent.Loc, err)
+			}
+
+			found := false
+
+			_ = index.ForEach(v.idx, func(other *index.Entry) error {
+				if found = m(other); found {
+					return index.ErrStop
+				}
+
+				return nil
+			})
+
+			return found, nil
+		})
+	}
+
+	return checks, nil
+}
+
+func nonSearchCheck(ok func(*indexView, *index.Entry) bool) indexCheck {
+	return func(_ context.Context, v *indexView, ent *index.Entry) (bool, error) {
+		if _, search := ent.IsSearch(); search {
+			return true, nil
+		}
+
+		return ok(v, ent), nil
+	}
+}
@@ -0,0 +1,98 @@
+package linter
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestLintIndex(t *testing.T) {
+	ent := func(name, path string, attrs index.Attrs) *index.Entry {
+		return &index.Entry{Name: name, Attrs: attrs, Loc: scanner.Loc{Path: path, Line: 1, StartColumn: 1, EndColumn: 1}}
+	}
+
+	idx := index.NewIndex([]*index.Entry{
+		ent("pair", "a", nil),
+		ent("pair", "b", nil),
+		ent("single", "a", nil),
+		ent("local", "a", index.Attrs{"scope": "a"}),
+		ent("local", "b", index.Attrs{"scope": "b"}),
+		ent("p*", "c", index.Attrs{"search": "glob"}),
+		ent("z*", "c", index.Attrs{"search": "glob"}),
+	})
+
+	tests := []struct {
+		name  string
+		rule  Rule
+		fails []string // paths and names of failing entries.
+	}{
+		{
+			name:  "min count",
+			rule:  Rule{MinCount: 2},
+			fails: []string{"a single"},
+		},
+		{
+			name:  "no orphans",
+			rule:  Rule{NoOrphans: true},
+			fails: []string{"a local", "a single", "b local"},
+		},
+		{
+			name:  "unique in scope",
+			rule:  Rule{UniqueInScope: true},
+			fails: []string{"a pair", "b pair"},
+		},
+		{
+			name:  "unique in scope, path filtered",
+			rule:  Rule{UniqueInScope: true, PathGlob: "a"},
+			fails: []string{"a pair"},
+		},
+		{
+			name:  "search must match",
+			rule:  Rule{SearchMustMatch: true},
+			fails: []string{"c z*"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := NewLinter(zlog.NewNopLogger(), Config{Rules: []Rule{test.rule}})
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			fails, err := l.LintIndex(context.Background(), idx)
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			var got []string
+			for ent := range fails {
+				got = append(got, ent.Loc.Path+" "+ent.Name)
+			}
+
+			sort.Strings(got)
+
+			if len(got) != len(test.fails) {
+				t.Fatalf("fails: %v != %v", got, test.fails)
+			}
+
+			for i := range got {
+				if got[i] != test.fails[i] {
+					t.Errorf("fails: %v != %v", got, test.fails)
+				}
+			}
+
+			// entry checks always pass for index-only rules.
+			for _, ent := range idx.Slice() {
+				if pass, err := l.LintRule(context.Background(), 0, ent); err != nil || !pass {
+					t.Errorf("%v: entry check failed: %v %v", ent.Loc, pass, err)
+				}
+			}
+		})
+	}
+}
@@ -2,21 +2,29 @@ package linter
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
-	"os/exec"
 	"regexp"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"text/template"
 
 	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
 
 	"github.com/cluttercode/clutter/pkg/strmatcher"
 	"github.com/cluttercode/clutter/pkg/zlog"
 )
 
 type internalRule struct {
+	message   *template.Template // nil if no message.
 	checkPath func(string) bool
-	eval      func(context.Context, *index.Entry) (bool, error)
+	eval      func(context.Context, *index.Entry) (bool, error)             // nil if no entry checks.
+	evalBatch func(context.Context, []*index.Entry) ([]bool, error)         // nil if not a batch rule.
+	evalIndex func(context.Context, *indexView, *index.Entry) (bool, error) // nil if no index checks.
+	fix       func(context.Context, *index.Entry) (string, error)           // nil if no fix.
 }
 
 type Linter struct {
@@ -30,12 +38,23 @@ type Linter struct {
 func (ir *internalRule) init(l *Linter, r Rule) error {
 	l.z.Debugw("init rule", "r", r)
 
-	if r.PathGlob != "" && r.PathRegexp != "" {
-		return fmt.Errorf("path-pattern and path-re are mutuallye exclusive")
+	switch r.Severity {
+	case SeverityError, SeverityWarning, SeverityInfo:
+	default:
+		return fmt.Errorf("invalid severity %q", r.Severity)
 	}
 
-	if len(r.Shell) == 0 {
-		return fmt.Errorf("shell is expected")
+	if r.Message != "" {
+		tmpl, err := template.New("message").Option("missingkey=zero").Parse(r.Message)
+		if err != nil {
+			return fmt.Errorf("message: %w", err)
+		}
+
+		ir.message = tmpl
+	}
+
+	if r.PathGlob != "" && r.PathRegexp != "" {
+		return fmt.Errorf("path-pattern and path-re are mutuallye exclusive")
 	}
 
 	ir.checkPath = func(string) bool { return true }
@@ -70,9 +89,66 @@ func (ir *internalRule) init(l *Linter, r Rule) error {
 		}
 	}
 
-	if cmd := r.Shell; len(cmd) > 0 {
+	checks, err := builtinChecks(r)
+	if err != nil {
+		return err
+	}
+
+	if r.Context < 0 {
+		return fmt.Errorf("context: must not be negative")
+	}
+
+	if r.Batch && len(r.Shell) == 0 {
+		return fmt.Errorf("batch requires shell")
+	}
+
+	// shell is last, as it is the most expensive.
+	if len(r.Shell) > 0 {
+		if r.Batch {
+			ir.evalBatch = func(ctx context.Context, ents []*index.Entry) ([]bool, error) {
+				return l.shellBatch(ctx, &r, ents)
+			}
+		} else {
+			checks = append(checks, func(ctx context.Context, ent *index.Entry) (bool, error) {
+				return l.shell(ctx, &r, ent)
+			})
+		}
+	}
+
+	ichecks, err := indexChecks(r)
+	if err != nil {
+		return err
+	}
+
+	if ir.fix, err = l.newFix(&r); err != nil {
+		return fmt.Errorf("fix: %w", err)
+	}
+
+	if len(checks) == 0 && len(ichecks) == 0 && ir.evalBatch == nil {
+		return fmt.Errorf("no checks specified")
+	}
+
+	if len(checks) > 0 {
 		ir.eval = func(ctx context.Context, ent *index.Entry) (bool, error) {
-			return l.shell(ctx, cmd, ent)
+			for _, c := range checks {
+				if ok, err := c(ctx, ent); err != nil || !ok {
+					return false, err
+				}
+			}
+
+			return true, nil
+		}
+	}
+
+	if len(ichecks) > 0 {
+		ir.evalIndex = func(ctx context.Context, v *indexView, ent *index.Entry) (bool, error) {
+			for _, c := range ichecks {
+				if ok, err := c(ctx, v, ent); err != nil || !ok {
+					return false, err
+				}
+			}
+
+			return true, nil
 		}
 	}
 
@@ -80,6 +156,16 @@ func (ir *internalRule) init(l *Linter, r Rule) error {
 }
 
 func NewLinter(z *zlog.Logger, cfg Config) (*Linter, error) {
+	rules := append([]Rule(nil), cfg.Rules...)
+
+	for i := range rules {
+		if rules[i].Severity == "" {
+			rules[i].Severity = SeverityError
+		}
+	}
+
+	cfg.Rules = rules
+
 	l := &Linter{
 		z:      z,
 		config: cfg,
@@ -103,6 +189,54 @@ func (l *Linter) Rule(i int) *Rule {
 	return &l.config.Rules[i]
 }
 
+// RuleName returns the name of the ith rule, or its number if it has no name.
+func (l *Linter) RuleName(i int) string {
+	if name := l.config.Rules[i].Name; name != "" {
+		return name
+	}
+
+	return fmt.Sprintf("#%d", i)
+}
+
+// messageData is the data passed to rule message templates.
+type messageData struct {
+	Name         string
+	Attrs        index.Attrs
+	Loc          scanner.Loc
+	Path         string
+	Line, Column int
+	Rule         string
+}
+
+// Message returns the message of the ith rule for ent, which violates it.
+// An empty string is returned if the rule has no message.
+func (l *Linter) Message(i int, ent *index.Entry) (string, error) {
+	tmpl := l.rules[i].message
+	if tmpl == nil {
+		return "", nil
+	}
+
+	var b strings.Builder
+
+	if err := tmpl.Execute(&b, messageData{
+		Name:   ent.Name,
+		Attrs:  ent.Attrs,
+		Loc:    ent.Loc,
+		Path:   ent.Loc.Path,
+		Line:   ent.Loc.Line,
+		Column: ent.Loc.StartColumn,
+		Rule:   l.RuleName(i),
+	}); err != nil {
+		return "", fmt.Errorf("rule %s: message: %w", l.RuleName(i), err)
+	}
+
+	return b.String(), nil
+}
+
+// ErrTimeout is returned, wrapped, by LintRule if the rule's timeout
+// expires.
+var ErrTimeout = errors.New("timed out")
+
 func (l *Linter) LintRule(ctx context.Context, i int, ent *index.Entry) (bool, error) {
 	rule := l.rules[i]
 
@@ -111,7 +245,30 @@ func (l *Linter) LintRule(ctx context.Context, i int, ent *index.Entry) (bool, e
 		return true, nil
 	}
 
-	pass, err := rule.eval(ctx, ent)
+	if rule.eval == nil && rule.evalBatch == nil {
+		return true, nil
+	}
+
+	ctx, cancel := l.withTimeout(ctx, i)
+	defer cancel()
+
+	pass, err := true, error(nil)
+
+	if rule.eval != nil {
+		pass, err = rule.eval(ctx, ent)
+	}
+
+	if pass && err == nil && rule.evalBatch != nil {
+		var passes []bool
+		if passes, err = rule.evalBatch(ctx, []*index.Entry{ent}); err == nil {
+			pass = passes[0]
+		}
+	}
+
+	if err := l.timedOut(ctx, i); err != nil {
+		return false, err
+	}
+
 	if err != nil {
 		return false, err
 	}
@@ -119,6 +276,236 @@ func (l *Linter) LintRule(ctx context.Context, i int, ent *index.Entry) (bool, e
 	return pass, nil
 }
 
+func (l *Linter) withTimeout(ctx context.Context, i int) (context.Context, context.CancelFunc) {
+	if timeout := l.config.Rules[i].Timeout; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// timedOut returns an error wrapping ErrTimeout if ctx, as returned by
+// withTimeout, expired. A killed shell command is indistinguishable from one
+// that fails, so ctx must be checked regardless of the command's result.
+func (l *Linter) timedOut(ctx context.Context, i int) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%w after %v", ErrTimeout, l.config.Rules[i].Timeout)
+	}
+
+	return nil
+}
+
+// Result is the outcome of evaluating all rules for an entry.
+type Result struct {
+	Fails    []int // indices of violated rules.
+	TimedOut []int // indices of rules that timed out.
+}
+
+// Union returns the sorted indices of the rules in any of fails, each once. A
+// rule with both entry and index checks might be violated by both.
+func Union(fails ...[]int) []int {
+	seen := make(map[int]bool)
+
+	var union []int
+
+	for _, fs := range fails {
+		for _, i := range fs {
+			if !seen[i] {
+				seen[i] = true
+				union = append(union, i)
+			}
+		}
+	}
+
+	sort.Ints(union)
+
+	return union
+}
+
+// LintEntries is Lint for all ents, evaluating each pair of entry and rule
+// concurrently using jobs workers, or the number of CPUs if jobs is not
+// positive. Results are in the order of ents. Timeouts are reported in the
+// results rather than as errors.
+func (l *Linter) LintEntries(ctx context.Context, ents []*index.Entry, jobs int) ([]Result, error) {
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	const (
+		pass = iota
+		fail
+		timedOut
+	)
+
+	outcomes := make([][]int, len(ents))
+	for i := range outcomes {
+		outcomes[i] = make([]int, len(l.rules))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		tasks = make(chan func())
+
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	setErr := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	lintPair := func(i, j int) {
+		ok, err := l.LintRule(ctx, j, ents[i])
+
+		switch {
+		case errors.Is(err, ErrTimeout):
+			l.z.Infow("rule timed out", "rule", l.RuleName(j), "loc", ents[i].Loc)
+			outcomes[i][j] = timedOut
+		case err != nil:
+			setErr(fmt.Errorf("%v: rule %s: %w", ents[i].Loc, l.RuleName(j), err))
+		case !ok:
+			outcomes[i][j] = fail
+		}
+	}
+
+	// batch rules run once for all entries that pass their other checks. The
+	// timeout applies to the whole batch.
+	lintBatch := func(j int) {
+		rule := l.rules[j]
+
+		var (
+			is         []int
+			candidates []*index.Entry
+		)
+
+		for i, ent := range ents {
+			if !rule.checkPath(ent.Loc.Path) {
+				continue
+			}
+
+			if rule.eval != nil {
+				ok, err := rule.eval(ctx, ent)
+				if err != nil {
+					setErr(fmt.Errorf("%v: rule %s: %w", ent.Loc, l.RuleName(j), err))
+					return
+				}
+
+				if !ok {
+					outcomes[i][j] = fail
+					continue
+				}
+			}
+
+			is = append(is, i)
+			candidates = append(candidates, ent)
+		}
+
+		bctx, cancel := l.withTimeout(ctx, j)
+		defer cancel()
+
+		passes, err := rule.evalBatch(bctx, candidates)
+
+		if l.timedOut(bctx, j) != nil {
+			l.z.Infow("batch rule timed out", "rule", l.RuleName(j), "n", len(candidates))
+
+			for _, i := range is {
+				outcomes[i][j] = timedOut
+			}
+
+			return
+		}
+
+		if err != nil {
+			setErr(fmt.Errorf("rule %s: %w", l.RuleName(j), err))
+			return
+		}
+
+		for k, i := range is {
+			if !passes[k] {
+				outcomes[i][j] = fail
+			}
+		}
+	}
+
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for task := range tasks {
+				task()
+			}
+		}()
+	}
+
+	submit := func(task func()) bool {
+		select {
+		case tasks <- task:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+F:
+	for j, rule := range l.rules {
+		if rule.evalBatch != nil {
+			j := j
+			if !submit(func() { lintBatch(j) }) {
+				break F
+			}
+		}
+	}
+
+G:
+	for i := range ents {
+		for j, rule := range l.rules {
+			if rule.evalBatch != nil {
+				continue
+			}
+
+			i, j := i, j
+			if !submit(func() { lintPair(i, j) }) {
+				break G
+			}
+		}
+	}
+
+	close(tasks)
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(ents))
+
+	for i, rs := range outcomes {
+		for j, o := range rs {
+			switch o {
+			case fail:
+				results[i].Fails = append(results[i].Fails, j)
+			case timedOut:
+				results[i].TimedOut = append(results[i].TimedOut, j)
+			}
+		}
+	}
+
+	return results, nil
+}
+
 func (l *Linter) Lint(ctx context.Context, ent *index.Entry) ([]int, error) {
 	var fails []int
 
@@ -145,45 +532,44 @@ func (l *Linter) Lint(ctx context.Context, ent *index.Entry) ([]int, error) {
 	return fails, nil
 }
 
-func entVars(ent *index.Entry) map[string]interface{} {
-	m := map[string]interface{}{
-		"NAME": ent.Name,
-		"PATH": ent.Loc.Path,
-	}
+// LintIndex evaluates the index checks of all rules against all entries in
+// idx. It returns the indices of the rules violated by each entry, only for
+// entries that violate any.
+func (l *Linter) LintIndex(ctx context.Context, idx *index.Index) (map[*index.Entry][]int, error) {
+	fails := make(map[*index.Entry][]int)
 
-	for k, v := range ent.Attrs {
-		m[strings.ToUpper(fmt.Sprintf("ATTR_%s", k))] = v
-	}
+	var v *indexView
 
-	return m
-}
+	for i, rule := range l.rules {
+		if rule.evalIndex == nil {
+			continue
+		}
 
-func (l *Linter) shell(ctx context.Context, cmdParts []string, ent *index.Entry) (bool, error) {
-	vars := entVars(ent)
+		if v == nil {
+			v = newIndexView(idx)
+		}
 
-	varsList := make([]string, 0, len(vars))
-	for k, v := range vars {
-		varsList = append(varsList, fmt.Sprintf("ENT_%s=%s", k, v))
-	}
+		l.z.Debugw("checking index rule", "name", l.config.Rules[i].Name, "i", i)
 
-	cmd := exec.CommandContext(ctx, cmdParts[0], cmdParts[1:]...)
+		if err := index.ForEach(idx, func(ent *index.Entry) error {
+			if !rule.checkPath(ent.Loc.Path) {
+				return nil
+			}
 
-	cmd.Env = append(os.Environ(), varsList...)
+			ok, err := rule.evalIndex(ctx, v, ent)
+			if err != nil {
+				return fmt.Errorf("rule %d: %w", i, err)
+			}
 
-	l.z.Infow("execution shell lint rule", "cmd", cmd)
+			if !ok {
+				fails[ent] = append(fails[ent], i)
+			}
 
-	if err := cmd.Run(); err != nil {
-		if _, ok := err.(*exec.ExitError); ok {
-			l.z.Info("returned non-zero")
-			return false, nil
+			return nil
+		}); err != nil {
+			return nil, err
 		}
-
-		l.z.Errorw("shell error", "err", err)
-
-		return false, fmt.Errorf("shell: %w", err)
 	}
 
-	l.z.Info("returned zero")
-
-	return true, nil
+	return fails, nil
 }
@@ -0,0 +1,176 @@
+package linter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestMessage(t *testing.T) {
+	ent := &index.Entry{
+		Name:  "meow",
+		Attrs: index.Attrs{"owner": "zumi"},
+		Loc:   scanner.Loc{Path: "a.go", Line: 3, StartColumn: 4, EndColumn: 20},
+	}
+
+	tests := []struct {
+		rule Rule
+		exp  string
+		err  bool
+	}{
+		{
+			rule: Rule{NameRegexp: "x"},
+			exp:  "",
+		},
+		{
+			rule: Rule{Name: "r", NameRegexp: "x", Message: "{{.Rule}}: {{.Name}} at {{.Loc}} ({{.Path}}:{{.Line}}:{{.Column}}) by {{.Attrs.owner}}"},
+			exp:  "r: meow at a.go:3.4-20 (a.go:3:4) by zumi",
+		},
+		{
+			rule: Rule{NameRegexp: "x", Message: "{{.Rule}} missing [{{.Attrs.lang}}]"},
+			exp:  "#0 missing []",
+		},
+		{
+			rule: Rule{NameRegexp: "x", Message: "{{.Name"},
+			err:  true,
+		},
+		{
+			rule: Rule{NameRegexp: "x", Severity: "fatal"},
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rule.Message, func(t *testing.T) {
+			l, err := NewLinter(zlog.NewNopLogger(), Config{Rules: []Rule{test.rule}})
+
+			if test.err {
+				if err == nil {
+					t.Errorf("error expected, but got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			if sev := l.Rule(0).Severity; sev != SeverityError && test.rule.Severity == "" {
+				t.Errorf("default severity: %q", sev)
+			}
+
+			msg, err := l.Message(0, ent)
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			if msg != test.exp {
+				t.Errorf("%q != %q", msg, test.exp)
+			}
+		})
+	}
+}
+
+func TestSeverityAtLeast(t *testing.T) {
+	tests := []struct {
+		s, other Severity
+		exp      bool
+	}{
+		{SeverityError, SeverityError, true},
+		{SeverityError, SeverityWarning, true},
+		{SeverityWarning, SeverityError, false},
+		{SeverityInfo, SeverityWarning, false},
+		{SeverityWarning, SeverityInfo, true},
+		{SeverityError, SeverityNone, false},
+	}
+
+	for _, test := range tests {
+		if got := test.s.AtLeast(test.other); got != test.exp {
+			t.Errorf("%s at least %s: %v != %v", test.s, test.other, got, test.exp)
+		}
+	}
+}
+
+func TestUnion(t *testing.T) {
+	tests := []struct {
+		fails [][]int
+		exp   []int
+	}{
+		{},
+		{fails: [][]int{{2, 0}}, exp: []int{0, 2}},
+		{fails: [][]int{{1}, {1}}, exp: []int{1}},
+		{fails: [][]int{{0, 3}, nil, {3, 1}}, exp: []int{0, 1, 3}},
+	}
+
+	for _, test := range tests {
+		if got := Union(test.fails...); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("%v: %v != %v", test.fails, got, test.exp)
+		}
+	}
+}
+
+func TestLintEntries(t *testing.T) {
+	l, err := NewLinter(zlog.NewNopLogger(), Config{
+		Rules: []Rule{
+			{Name: "owner", RequiredAttrs: []string{"owner"}},
+			{Name: "slow", Shell: []string{"sh", "-c", `test "$ENT_NAME" != slow || sleep 5`}, Timeout: 50 * time.Millisecond},
+			{Name: "lang", Shell: []string{"sh", "-c", `test -n "$ENT_ATTR_LANG"`}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ents []*index.Entry
+		exp  []Result
+	)
+
+	for i := 0; i < 20; i++ {
+		ent := &index.Entry{Name: fmt.Sprintf("e%d", i), Attrs: index.Attrs{}}
+		res := Result{}
+
+		if i%2 == 0 {
+			ent.Attrs["owner"] = "x"
+		} else {
+			res.Fails = append(res.Fails, 0)
+		}
+
+		if i%5 == 0 {
+			ent.Name = "slow"
+			res.TimedOut = []int{1}
+		}
+
+		if i%3 == 0 {
+			ent.Attrs["lang"] = "go"
+		} else {
+			res.Fails = append(res.Fails, 2)
+		}
+
+		ents = append(ents, ent)
+		exp = append(exp, res)
+	}
+
+	start := time.Now()
+
+	got, err := l.LintEntries(context.Background(), ents, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := time.Since(start); d > 3*time.Second {
+		t.Errorf("took too long: %v", d)
+	}
+
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("%+v != %+v", got, exp)
+	}
+}
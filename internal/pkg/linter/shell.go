@@ -0,0 +1,304 @@
+package linter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+)
+
+// shellEntry describes an entry to shell rules. It is written as JSON to the
+// rule's stdin, and its fields are also set as ENT_* environment variables,
+// see entVars.
+type shellEntry struct {
+	Name      string            `json:"name"`
+	Path      string            `json:"path"`
+	Line      int               `json:"line"`
+	Column    int               `json:"column"`
+	EndLine   int               `json:"end_line"`
+	EndColumn int               `json:"end_column"`
+	Loc       string            `json:"loc"`
+	Attrs     map[string]string `json:"attrs"`
+	Search    string            `json:"search,omitempty"` // the search type, for search tags.
+	Entry     string            `json:"entry"`            // in the index format.
+
+	// Only if the rule asks for them.
+	Source      []string `json:"source,omitempty"`       // the lines of the tag.
+	Context     []string `json:"context,omitempty"`      // the lines of the tag and around it.
+	ContextLine int      `json:"context_line,omitempty"` // the number of the first context line.
+}
+
+func (l *Linter) newShellEntry(r *Rule, ent *index.Entry) *shellEntry {
+	search, _ := ent.IsSearch()
+
+	attrs := make(map[string]string, len(ent.Attrs))
+	for k, v := range ent.Attrs {
+		attrs[k] = v
+	}
+
+	se := &shellEntry{
+		Name:      ent.Name,
+		Path:      ent.Loc.Path,
+		Line:      ent.Loc.Line,
+		Column:    ent.Loc.StartColumn,
+		EndLine:   ent.Loc.LastLine(),
+		EndColumn: ent.Loc.EndColumn,
+		Loc:       ent.Loc.String(),
+		Attrs:     attrs,
+		Search:    search,
+		Entry:     ent.String(),
+	}
+
+	if r.Source || r.Context > 0 {
+		if err := se.readSource(r.Context); err != nil {
+			// the file might not be on disk, for example if the index is of a
+			// git revision.
+			l.z.Debugw("cannot read source", "path", ent.Loc.Path, "err", err)
+		}
+	}
+
+	return se
+}
+
+func (se *shellEntry) readSource(n int) error {
+	bs, err := ioutil.ReadFile(se.Path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(string(bs), "\n")
+
+	slice := func(from, to int) []string { // 1-based, inclusive.
+		if from < 1 {
+			from = 1
+		}
+
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		if from > to {
+			return nil
+		}
+
+		ls := make([]string, 0, to-from+1)
+		for _, l := range lines[from-1 : to] {
+			ls = append(ls, strings.TrimRight(l, "\r\n"))
+		}
+
+		return ls
+	}
+
+	se.Source = slice(se.Line, se.EndLine)
+
+	if n > 0 {
+		se.ContextLine = se.Line - n
+		if se.ContextLine < 1 {
+			se.ContextLine = 1
+		}
+
+		se.Context = slice(se.ContextLine, se.EndLine+n)
+	}
+
+	return nil
+}
+
+// entVars returns the environment variables describing se, without the ENT_
+// prefix.
+func entVars(se *shellEntry) map[string]interface{} {
+	m := map[string]interface{}{
+		"NAME":       se.Name,
+		"PATH":       se.Path,
+		"LINE":       se.Line,
+		"COLUMN":     se.Column,
+		"END_LINE":   se.EndLine,
+		"END_COLUMN": se.EndColumn,
+		"LOC":        se.Loc,
+		"SEARCH":     se.Search,
+		"ENTRY":      se.Entry,
+	}
+
+	for k, v := range se.Attrs {
+		m[strings.ToUpper(fmt.Sprintf("ATTR_%s", k))] = v
+	}
+
+	if se.Source != nil {
+		m["SOURCE"] = strings.Join(se.Source, "\n")
+	}
+
+	if se.Context != nil {
+		m["CONTEXT"] = strings.Join(se.Context, "\n")
+		m["CONTEXT_LINE"] = se.ContextLine
+	}
+
+	return m
+}
+
+func (l *Linter) shell(ctx context.Context, r *Rule, ent *index.Entry) (bool, error) {
+	pass, _, err := l.runShell(ctx, r, ent)
+	return pass, err
+}
+
+// runShell runs the rule's command for ent, and returns whether it passed
+// and its stdout.
+func (l *Linter) runShell(ctx context.Context, r *Rule, ent *index.Entry) (bool, []byte, error) {
+	se := l.newShellEntry(r, ent)
+
+	vars := entVars(se)
+
+	varsList := make([]string, 0, len(vars))
+	for k, v := range vars {
+		varsList = append(varsList, fmt.Sprintf("ENT_%s=%v", k, v))
+	}
+
+	doc, err := json.Marshal(se)
+	if err != nil {
+		return false, nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, r.Shell[0], r.Shell[1:]...)
+
+	cmd.Env = append(os.Environ(), varsList...)
+	cmd.Stdin = bytes.NewReader(append(doc, '\n'))
+
+	l.z.Infow("execution shell lint rule", "cmd", cmd)
+
+	// stdout is only needed for fixes.
+	capture := r.Fix != nil && r.Fix.Shell
+
+	out, err := runCommand(cmd, capture)
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			l.z.Info("returned non-zero")
+			return false, out, nil
+		}
+
+		l.z.Errorw("shell error", "err", err)
+
+		return false, nil, fmt.Errorf("shell: %w", err)
+	}
+
+	l.z.Info("returned zero")
+
+	return true, out, nil
+}
+
+// runCommand runs cmd, and returns its stdout if capture is set. Stdout is
+// written to a temporary file rather than a pipe, as otherwise a killed
+// command would not return until all processes it started, which inherit the
+// pipe, exit.
+func runCommand(cmd *exec.Cmd, capture bool) ([]byte, error) {
+	if !capture {
+		return nil, cmd.Run()
+	}
+
+	fp, err := ioutil.TempFile("", "clutter-lint-")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(fp.Name())
+	defer fp.Close()
+
+	cmd.Stdout = fp
+
+	runErr := cmd.Run()
+
+	if _, err := fp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	out, err := ioutil.ReadAll(fp)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, runErr
+}
+
+// shellBatch runs the rule's command once for all ents. The command reads
+// the entries from stdin as JSON lines, and must write a line for each, in
+// the same order, that is either "pass" or "fail".
+func (l *Linter) shellBatch(ctx context.Context, r *Rule, ents []*index.Entry) ([]bool, error) {
+	answers, err := l.runBatch(ctx, r, ents)
+	if err != nil {
+		return nil, err
+	}
+
+	passes := make([]bool, len(answers))
+	for i, a := range answers {
+		passes[i] = a.pass
+	}
+
+	return passes, nil
+}
+
+// batchAnswer is a line written by a batch command. A failing line may be
+// followed by the replacement text of the tag, see Fix.Shell.
+type batchAnswer struct {
+	pass bool
+	fix  string
+}
+
+func (l *Linter) runBatch(ctx context.Context, r *Rule, ents []*index.Entry) ([]batchAnswer, error) {
+	if len(ents) == 0 {
+		return nil, nil
+	}
+
+	var in bytes.Buffer
+
+	enc := json.NewEncoder(&in)
+
+	for _, ent := range ents {
+		if err := enc.Encode(l.newShellEntry(r, ent)); err != nil {
+			return nil, fmt.Errorf("marshal: %w", err)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, r.Shell[0], r.Shell[1:]...)
+
+	cmd.Stdin = &in
+
+	l.z.Infow("execution batch shell lint rule", "cmd", cmd, "n", len(ents))
+
+	out, err := runCommand(cmd, true)
+	if err != nil {
+		return nil, fmt.Errorf("shell: %w", err)
+	}
+
+	results := make([]batchAnswer, 0, len(ents))
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+
+	for sc.Scan() {
+		answer := strings.TrimSpace(sc.Text())
+
+		switch {
+		case answer == "pass":
+			results = append(results, batchAnswer{pass: true})
+		case answer == "fail" || strings.HasPrefix(answer, "fail "):
+			results = append(results, batchAnswer{fix: strings.TrimSpace(answer[len("fail"):])})
+		default:
+			return nil, fmt.Errorf("shell: line %d: expected pass or fail, got %s", len(results)+1, strconv.Quote(answer))
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("shell: %w", err)
+	}
+
+	if len(results) != len(ents) {
+		return nil, fmt.Errorf("shell: expected %d answers, got %d", len(ents), len(results))
+	}
+
+	return results, nil
+}
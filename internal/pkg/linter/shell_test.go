@@ -0,0 +1,127 @@
+package linter
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestShell(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clutter-linter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a.go")
+
+	if err := ioutil.WriteFile(path, []byte("one\ntwo\nthree tag\nfour\nfive\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ent := &index.Entry{
+		Name:  "x",
+		Attrs: index.Attrs{"lang": "go"},
+		Loc:   scanner.Loc{Path: path, Line: 3, StartColumn: 7, EndColumn: 9},
+	}
+
+	tests := []struct {
+		name string
+		rule Rule
+		exp  bool
+	}{
+		{
+			name: "env",
+			rule: Rule{Shell: []string{"sh", "-c", `test "$ENT_NAME/$ENT_LINE/$ENT_COLUMN/$ENT_END_COLUMN/$ENT_ATTR_LANG" = x/3/7/9/go`}},
+			exp:  true,
+		},
+		{
+			name: "stdin",
+			rule: Rule{Shell: []string{"grep", "-q", `"name":"x".*"attrs":{"lang":"go"}`}},
+			exp:  true,
+		},
+		{
+			name: "no source",
+			rule: Rule{Shell: []string{"sh", "-c", `test -z "$ENT_SOURCE"`}},
+			exp:  true,
+		},
+		{
+			name: "source",
+			rule: Rule{Shell: []string{"sh", "-c", `test "$ENT_SOURCE" = "three tag"`}, Source: true},
+			exp:  true,
+		},
+		{
+			name: "context",
+			rule: Rule{Shell: []string{"grep", "-q", `"context":\["two","three tag","four"\],"context_line":2`}, Context: 1},
+			exp:  true,
+		},
+		{
+			name: "fail",
+			rule: Rule{Shell: []string{"sh", "-c", `test "$ENT_NAME" = y`}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := NewLinter(zlog.NewNopLogger(), Config{Rules: []Rule{test.rule}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ok, err := l.LintRule(context.Background(), 0, ent)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok != test.exp {
+				t.Errorf("%v != %v", ok, test.exp)
+			}
+		})
+	}
+}
+
+func TestShellBatch(t *testing.T) {
+	// fails entries named bad.
+	script := `while read -r l; do case "$l" in *'"name":"bad"'*) echo fail;; *) echo pass;; esac; done`
+
+	l, err := NewLinter(zlog.NewNopLogger(), Config{
+		Rules: []Rule{
+			{Name: "batch", Shell: []string{"sh", "-c", script}, Batch: true, RequiredAttrs: []string{"owner"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ents := []*index.Entry{
+		{Name: "good", Attrs: index.Attrs{"owner": "x"}},
+		{Name: "bad", Attrs: index.Attrs{"owner": "x"}},
+		{Name: "good", Attrs: index.Attrs{}},
+	}
+
+	got, err := l.LintEntries(context.Background(), ents, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := []Result{{}, {Fails: []int{0}}, {Fails: []int{0}}}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("%+v != %+v", got, exp)
+	}
+
+	if ok, err := l.LintRule(context.Background(), 0, ents[1]); err != nil || ok {
+		t.Errorf("single: %v, %v", ok, err)
+	}
+
+	if _, err := NewLinter(zlog.NewNopLogger(), Config{Rules: []Rule{{Batch: true}}}); err == nil {
+		t.Errorf("batch without shell: error expected")
+	}
+}
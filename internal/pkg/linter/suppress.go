@@ -0,0 +1,111 @@
+package linter
+
+import (
+	"github.com/cluttercode/clutter/internal/pkg/index"
+)
+
+// Suppressions filters violations suppressed by the entries themselves or by
+// their files, and keeps track of the suppressions that were used.
+type Suppressions struct {
+	l *Linter
+
+	used     map[*index.Entry]map[string]bool
+	usedFile map[string]map[string]bool // path -> rule names.
+}
+
+// Unused is a suppression of a rule that is never violated where it applies.
+type Unused struct {
+	Entry *index.Entry
+	Rule  string
+	File  bool // true for nolint-file.
+}
+
+func (l *Linter) NewSuppressions() *Suppressions {
+	return &Suppressions{
+		l:        l,
+		used:     make(map[*index.Entry]map[string]bool),
+		usedFile: make(map[string]map[string]bool),
+	}
+}
+
+// Filter returns the indices of the rules in fails, as returned by
+// Linter.Lint, that ent does not suppress.
+func (s *Suppressions) Filter(ent *index.Entry, fails []int) []int {
+	tag, file := ent.NoLint, ent.NoLintFile
+	if len(tag) == 0 && len(file) == 0 {
+		return fails
+	}
+
+	var kept []int
+
+	for _, i := range fails {
+		name := s.l.RuleName(i)
+
+		switch {
+		case contains(tag, name):
+			mark(s.used, ent, name)
+		case contains(file, name):
+			markFile(s.usedFile, ent.Loc.Path, name)
+		default:
+			kept = append(kept, i)
+		}
+	}
+
+	return kept
+}
+
+// Unused returns the suppressions in idx that were not used by Filter.
+// Unused file suppressions are reported once per file, at the first entry
+// that has them.
+func (s *Suppressions) Unused(idx *index.Index) []*Unused {
+	var unused []*Unused
+
+	reported := make(map[string]map[string]bool)
+
+	_ = index.ForEach(idx, func(ent *index.Entry) error {
+		tag, file := ent.NoLint, ent.NoLintFile
+
+		for _, name := range tag {
+			if !s.used[ent][name] {
+				unused = append(unused, &Unused{Entry: ent, Rule: name})
+			}
+		}
+
+		for _, name := range file {
+			if !s.usedFile[ent.Loc.Path][name] && !reported[ent.Loc.Path][name] {
+				markFile(reported, ent.Loc.Path, name)
+				unused = append(unused, &Unused{Entry: ent, Rule: name, File: true})
+			}
+		}
+
+		return nil
+	})
+
+	return unused
+}
+
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func mark(m map[*index.Entry]map[string]bool, ent *index.Entry, name string) {
+	if m[ent] == nil {
+		m[ent] = make(map[string]bool)
+	}
+
+	m[ent][name] = true
+}
+
+func markFile(m map[string]map[string]bool, path, name string) {
+	if m[path] == nil {
+		m[path] = make(map[string]bool)
+	}
+
+	m[path][name] = true
+}
@@ -0,0 +1,65 @@
+package linter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestSuppressions(t *testing.T) {
+	l, err := NewLinter(zlog.NewNopLogger(), Config{
+		Rules: []Rule{
+			{Name: "owner", RequiredAttrs: []string{"owner"}},
+			{Name: "lang", RequiredAttrs: []string{"lang"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ent := func(name, path string, line int, attrs index.Attrs, tag, file []string) *index.Entry {
+		return &index.Entry{Name: name, Attrs: attrs, Loc: scanner.Loc{Path: path, Line: line, StartColumn: 1, EndColumn: 1}, NoLint: tag, NoLintFile: file}
+	}
+
+	idx := index.NewIndex([]*index.Entry{
+		ent("a", "f", 1, nil, []string{"owner", "nosuchrule"}, nil),
+		ent("b", "f", 2, index.Attrs{"owner": "x"}, []string{"owner"}, []string{"lang"}),
+		ent("c", "f", 3, index.Attrs{"owner": "x"}, nil, []string{"lang"}),
+		ent("d", "g", 1, index.Attrs{"owner": "x", "lang": "go"}, nil, []string{"lang"}),
+	})
+
+	s := l.NewSuppressions()
+
+	var got []string
+
+	for _, e := range idx.Slice() {
+		fails, err := l.Lint(context.Background(), e)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, i := range s.Filter(e, fails) {
+			got = append(got, fmt.Sprintf("%s %s", e.Name, l.RuleName(i)))
+		}
+	}
+
+	if exp := []string{"a lang"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("violations: %v != %v", got, exp)
+	}
+
+	got = nil
+
+	for _, u := range s.Unused(idx) {
+		got = append(got, fmt.Sprintf("%s %s %v", u.Entry.Name, u.Rule, u.File))
+	}
+
+	if exp := []string{"a nosuchrule false", "b owner false", "d lang true"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("unused: %v != %v", got, exp)
+	}
+}
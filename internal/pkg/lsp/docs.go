@@ -0,0 +1,132 @@
+package lsp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+)
+
+// uriToPath converts a file URI to a path relative to root, which is how
+// paths appear in the index. Paths outside of root are returned absolute.
+func (s *Server) uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("uri: %w", err)
+	}
+
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported uri scheme %q", u.Scheme)
+	}
+
+	path := filepath.FromSlash(u.Path)
+
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path, nil
+	}
+
+	return rel, nil
+}
+
+func (s *Server) pathToURI(path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.root, path)
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// line returns the text of a 1 based line in the file at path, taking it from
+// the open document if there is one. Unreadable files yield empty lines.
+func (s *Server) line(path string, n int) string {
+	text, ok := s.docs[path]
+	if !ok {
+		bs, err := ioutil.ReadFile(filepath.Join(s.root, path))
+		if err != nil {
+			s.z.Debugw("cannot read file for positions", "path", path, "err", err)
+			return ""
+		}
+
+		text = string(bs)
+	}
+
+	lines := strings.SplitN(text, "\n", n+1)
+	if n < 1 || n > len(lines) {
+		return ""
+	}
+
+	return lines[n-1]
+}
+
+// utf16Len returns the length in UTF-16 code units of the first n bytes of
+// line. Bytes past the end of the line are counted as one unit each.
+func utf16Len(line string, n int) int {
+	if n <= 0 {
+		return 0
+	}
+
+	if n > len(line) {
+		return utf16Len(line, len(line)) + n - len(line)
+	}
+
+	units := 0
+
+	for _, r := range line[:n] {
+		units += runeUnits(r)
+	}
+
+	return units
+}
+
+// runeUnits returns the number of UTF-16 code units encoding r.
+func runeUnits(r rune) int {
+	if r >= 0x10000 {
+		return 2 // surrogate pair.
+	}
+
+	return 1
+}
+
+// byteOffset is the inverse of utf16Len.
+func byteOffset(line string, units int) int {
+	for i, r := range line {
+		if units <= 0 {
+			return i
+		}
+
+		units -= runeUnits(r)
+	}
+
+	return len(line) + units
+}
+
+func (s *Server) locToRange(loc scanner.Loc) Range {
+	last := loc.LastLine()
+
+	return Range{
+		Start: Position{
+			Line:      loc.Line - 1,
+			Character: utf16Len(s.line(loc.Path, loc.Line), loc.StartColumn-1),
+		},
+		End: Position{
+			Line:      last - 1,
+			Character: utf16Len(s.line(loc.Path, last), loc.EndColumn),
+		},
+	}
+}
+
+func (s *Server) locToLocation(loc scanner.Loc) Location {
+	return Location{URI: s.pathToURI(loc.Path), Range: s.locToRange(loc)}
+}
+
+// posToLoc returns a single column loc at the LSP position pos.
+func (s *Server) posToLoc(path string, pos Position) scanner.Loc {
+	line := pos.Line + 1
+	col := byteOffset(s.line(path, line), pos.Character) + 1
+
+	return scanner.Loc{Path: path, Line: line, StartColumn: col, EndColumn: col}
+}
@@ -0,0 +1,571 @@
+// Package lsp implements a language server for clutter tags, speaking the
+// language server protocol over a byte stream, usually stdio.
+//
+// The server keeps an in-memory index, initially given to it, in which the
+// entries of open documents are replaced by the entries scanned from their
+// editor buffers on every change.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/linter"
+	"github.com/cluttercode/clutter/internal/pkg/parser"
+	"github.com/cluttercode/clutter/internal/pkg/resolver"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+const diagnosticSource = "clutter"
+
+type Server struct {
+	z      *zlog.Logger
+	root   string
+	cfg    scanner.Config
+	linter *linter.Linter // nil if there are no lint rules.
+	filter func(string, os.FileInfo) (bool, error)
+
+	idx  *index.Index
+	docs map[string]string // path -> text of open documents.
+
+	// per path, last diagnostics from the parser and from the linter.
+	parseDiags, lintDiags map[string][]Diagnostic
+
+	conn     *conn
+	shutdown bool
+}
+
+// NewServer creates a server for the tree at root, which paths in idx are
+// relative to. l may be nil, in which case no lint diagnostics are reported.
+func NewServer(z *zlog.Logger, root string, cfg scanner.Config, l *linter.Linter, idx *index.Index) (*Server, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("root: %w", err)
+	}
+
+	filter, err := scanner.NewFilter(z, cfg, root)
+	if err != nil {
+		return nil, fmt.Errorf("filter: %w", err)
+	}
+
+	if idx == nil {
+		idx = index.NewIndex(nil)
+	}
+
+	return &Server{
+		z:          z,
+		root:       absRoot,
+		cfg:        cfg,
+		linter:     l,
+		filter:     filter,
+		idx:        idx,
+		docs:       make(map[string]string),
+		parseDiags: make(map[string][]Diagnostic),
+		lintDiags:  make(map[string][]Diagnostic),
+	}, nil
+}
+
+// Serve reads requests from r and writes responses and notifications to w
+// until the client sends exit or r is exhausted. Requests are handled one at
+// a time.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+
+	for {
+		msg, err := s.conn.read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			var rerr *rpcError
+			if errors.As(err, &rerr) {
+				if err := s.conn.reply(nil, nil, rerr); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			return fmt.Errorf("read: %w", err)
+		}
+
+		z := s.z.With("method", msg.Method)
+
+		if msg.Method == "exit" {
+			z.Info("exiting")
+			return nil
+		}
+
+		result, err := s.handle(ctx, z, msg)
+
+		if msg.ID == nil {
+			// notification, nothing to reply.
+			if err != nil {
+				z.Warnw("notification failed", "err", err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			z.Infow("request failed", "err", err)
+		}
+
+		if err := s.conn.reply(msg.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(ctx context.Context, z *zlog.Logger, msg *message) (interface{}, error) {
+	z.Debugw("handling", "params", string(msg.Params))
+
+	if s.shutdown && msg.ID != nil {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "server is shutting down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return s.initialize(msg.Params)
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		if err := unmarshalParams(msg.Params, &p); err != nil {
+			return nil, err
+		}
+
+		return nil, s.update(ctx, p.TextDocument.URI, p.TextDocument.Text, true)
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		if err := unmarshalParams(msg.Params, &p); err != nil {
+			return nil, err
+		}
+
+		if n := len(p.ContentChanges); n > 0 {
+			// full sync: the last change has the entire text.
+			return nil, s.update(ctx, p.TextDocument.URI, p.ContentChanges[n-1].Text, false)
+		}
+
+		return nil, nil
+	case "textDocument/didSave":
+		var p DidSaveTextDocumentParams
+		if err := unmarshalParams(msg.Params, &p); err != nil {
+			return nil, err
+		}
+
+		return nil, s.save(ctx, p.TextDocument.URI, p.Text)
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		if err := unmarshalParams(msg.Params, &p); err != nil {
+			return nil, err
+		}
+
+		return nil, s.close(ctx, p.TextDocument.URI)
+	case "textDocument/definition":
+		return s.resolve(msg.Params, resolver.ResolveNext)
+	case "textDocument/declaration":
+		return s.resolve(msg.Params, resolver.ResolvePrev)
+	case "textDocument/references":
+		return s.resolve(msg.Params, func(z *zlog.Logger, what *index.Entry, idx *index.Index, _ bool) ([]*index.Entry, error) {
+			return resolver.ResolveList(z, what, idx)
+		})
+	case "textDocument/hover":
+		return s.hover(msg.Params)
+	case "textDocument/documentSymbol":
+		return s.documentSymbols(msg.Params)
+	case "workspace/symbol":
+		return s.workspaceSymbols(msg.Params)
+	}
+
+	if msg.ID == nil {
+		// unknown notifications, such as initialized, are ignored.
+		return nil, nil
+	}
+
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not supported", msg.Method)}
+}
+
+func unmarshalParams(params json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	var p InitializeParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if p.RootURI != "" {
+		if path, err := s.uriToPath(p.RootURI); err != nil || path != "." {
+			s.z.Warnw("client root differs from server root, paths are relative to server root", "client", p.RootURI, "server", s.root)
+		}
+	}
+
+	var r InitializeResult
+
+	r.Capabilities = ServerCapabilities{
+		TextDocumentSync:        syncFull,
+		DefinitionProvider:      true,
+		DeclarationProvider:     true,
+		ReferencesProvider:      true,
+		HoverProvider:           true,
+		DocumentSymbolProvider:  true,
+		WorkspaceSymbolProvider: true,
+	}
+
+	r.ServerInfo.Name = "clutter"
+
+	return &r, nil
+}
+
+// update replaces the entries of the document at uri in the index with the
+// entries scanned from text, and publishes its diagnostics. Lint rules are
+// evaluated only if lint is set, since they might be expensive to run on
+// every change. Otherwise previous lint diagnostics are dropped as their
+// positions might be stale.
+func (s *Server) update(ctx context.Context, uri, text string, lint bool) error {
+	path, err := s.uriToPath(uri)
+	if err != nil {
+		return err
+	}
+
+	s.docs[path] = text
+
+	return s.reindex(ctx, uri, path, text, lint)
+}
+
+func (s *Server) save(ctx context.Context, uri string, text *string) error {
+	path, err := s.uriToPath(uri)
+	if err != nil {
+		return err
+	}
+
+	if text != nil {
+		s.docs[path] = *text
+	}
+
+	doc, ok := s.docs[path]
+	if !ok {
+		return nil
+	}
+
+	return s.reindex(ctx, uri, path, doc, true)
+}
+
+// close reindexes the document at uri from disk, as unsaved changes in the
+// buffer are gone.
+func (s *Server) close(ctx context.Context, uri string) error {
+	path, err := s.uriToPath(uri)
+	if err != nil {
+		return err
+	}
+
+	delete(s.docs, path)
+
+	var text string
+
+	if bs, err := ioutil.ReadFile(filepath.Join(s.root, path)); err == nil {
+		text = string(bs)
+	} else {
+		s.z.Infow("closed document unreadable, dropping its entries", "path", path, "err", err)
+	}
+
+	if err := s.reindex(ctx, uri, path, text, false); err != nil {
+		return err
+	}
+
+	// no diagnostics are reported for closed documents.
+	delete(s.parseDiags, path)
+	delete(s.lintDiags, path)
+
+	return s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{URI: uri, Diagnostics: []Diagnostic{}})
+}
+
+func (s *Server) reindex(ctx context.Context, uri, path, text string, lint bool) error {
+	z := s.z.With("path", path)
+
+	var (
+		ents  []*index.Entry
+		diags []Diagnostic
+	)
+
+	if ok, _ := s.filter(path, nil); ok {
+		if err := scanner.ScanReader(z, s.cfg, path, strings.NewReader(text), func(elem *scanner.RawElement) error {
+			ent, err := parser.ParseElement(elem)
+			if err != nil {
+				diags = append(diags, Diagnostic{
+					Range:    s.locToRange(elem.Loc),
+					Severity: SeverityError,
+					Source:   diagnosticSource,
+					Message:  err.Error(),
+				})
+
+				return nil
+			}
+
+			ents = append(ents, ent)
+
+			return nil
+		}); err != nil {
+			diags = append(diags, Diagnostic{
+				Severity: SeverityError,
+				Source:   diagnosticSource,
+				Message:  err.Error(),
+			})
+		}
+	} else {
+		z.Debug("path is excluded from scanning")
+	}
+
+	idx, _ := index.Filter(s.idx, func(ent *index.Entry) (bool, error) { return ent.Loc.Path != path, nil })
+	s.idx = idx.Add(ents)
+
+	z.Debugw("reindexed", "n", len(ents))
+
+	s.parseDiags[path] = diags
+
+	if lint {
+		lintDiags, err := s.lint(ctx, ents)
+		if err != nil {
+			return fmt.Errorf("lint: %w", err)
+		}
+
+		s.lintDiags[path] = lintDiags
+	} else {
+		delete(s.lintDiags, path)
+	}
+
+	return s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: append(append([]Diagnostic{}, s.parseDiags[path]...), s.lintDiags[path]...),
+	})
+}
+
+var lintSeverities = map[linter.Severity]int{
+	linter.SeverityError:   SeverityError,
+	linter.SeverityWarning: SeverityWarning,
+	linter.SeverityInfo:    SeverityInformation,
+}
+
+func (s *Server) lint(ctx context.Context, ents []*index.Entry) ([]Diagnostic, error) {
+	if s.linter == nil {
+		return nil, nil
+	}
+
+	var diags []Diagnostic
+
+	sups := s.linter.NewSuppressions()
+
+	for _, ent := range ents {
+		fails, err := s.linter.Lint(ctx, ent)
+		if err != nil {
+			return nil, err
+		}
+
+		fails = sups.Filter(ent, fails)
+
+		for _, i := range fails {
+			name := s.linter.RuleName(i)
+
+			msg, err := s.linter.Message(i, ent)
+			if err != nil {
+				return nil, err
+			}
+
+			if msg == "" {
+				msg = fmt.Sprintf("violates lint rule %s", name)
+			}
+
+			diags = append(diags, Diagnostic{
+				Range:    s.locToRange(ent.Loc),
+				Severity: lintSeverities[s.linter.Rule(i).Severity],
+				Code:     name,
+				Source:   diagnosticSource,
+				Message:  msg,
+			})
+		}
+	}
+
+	return diags, nil
+}
+
+// entryAt returns the entry at the given document position, or nil if there
+// is no tag there.
+func (s *Server) entryAt(p TextDocumentPositionParams) (*index.Entry, error) {
+	path, err := s.uriToPath(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := s.posToLoc(path, p.Position)
+
+	var what *index.Entry
+
+	_, _ = index.Filter(s.idx, func(ent *index.Entry) (bool, error) {
+		if ent.Loc.Contains(loc) {
+			what = ent
+			return false, index.ErrStop
+		}
+
+		return false, nil
+	})
+
+	s.z.Debugw("entry at", "loc", loc, "what", what)
+
+	return what, nil
+}
+
+type resolveFunc func(*zlog.Logger, *index.Entry, *index.Index, bool) ([]*index.Entry, error)
+
+func (s *Server) resolve(params json.RawMessage, r resolveFunc) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	what, err := s.entryAt(p)
+	if err != nil || what == nil {
+		return nil, err
+	}
+
+	ents, err := r(s.z.Named("resolver").With("what", what), what, s.idx, true)
+	if err != nil {
+		return nil, fmt.Errorf("resolver: %w", err)
+	}
+
+	locs := make([]Location, len(ents))
+	for i, ent := range ents {
+		locs[i] = s.locToLocation(ent.Loc)
+	}
+
+	return locs, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	what, err := s.entryAt(p)
+	if err != nil || what == nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+
+	if _, search := what.IsSearch(); search {
+		fmt.Fprintf(&b, "search **%s**", what.Name)
+	} else {
+		fmt.Fprintf(&b, "**%s**", what.Name)
+	}
+
+	if ents, err := resolver.ResolveList(s.z.Named("resolver"), what, s.idx); err == nil {
+		fmt.Fprintf(&b, " (%d in scope)", len(ents))
+	}
+
+	if len(what.Attrs) != 0 {
+		keys := make([]string, 0, len(what.Attrs))
+		for k := range what.Attrs {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		b.WriteString("\n")
+
+		for _, k := range keys {
+			fmt.Fprintf(&b, "\n- `%s`", index.AttrToString(k, what.Attrs[k]))
+		}
+	}
+
+	r := s.locToRange(what.Loc)
+
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: b.String()},
+		Range:    &r,
+	}, nil
+}
+
+func (s *Server) symbol(ent *index.Entry) SymbolInformation {
+	return SymbolInformation{
+		Name:          ent.Name,
+		Kind:          symbolKindKey,
+		Location:      s.locToLocation(ent.Loc),
+		ContainerName: ent.Attrs["scope"],
+	}
+}
+
+func (s *Server) documentSymbols(params json.RawMessage) (interface{}, error) {
+	var p DocumentSymbolParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	path, err := s.uriToPath(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	syms := []SymbolInformation{}
+
+	_ = index.ForEach(s.idx, func(ent *index.Entry) error {
+		if ent.Loc.Path == path {
+			syms = append(syms, s.symbol(ent))
+		}
+
+		return nil
+	})
+
+	return syms, nil
+}
+
+// workspaceSymbols finds all tags whose name contains the query, ignoring
+// case, using the same matching as search tags.
+func (s *Server) workspaceSymbols(params json.RawMessage) (interface{}, error) {
+	var p WorkspaceSymbolParams
+	if err := unmarshalParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	search := &index.Entry{
+		Name:  "(?i)" + regexp.QuoteMeta(p.Query),
+		Attrs: index.Attrs{"search": "regexp"},
+	}
+
+	match, err := search.Matcher()
+	if err != nil {
+		return nil, fmt.Errorf("matcher: %w", err)
+	}
+
+	syms := []SymbolInformation{}
+
+	_ = index.ForEach(s.idx, func(ent *index.Entry) error {
+		if match(ent) {
+			syms = append(syms, s.symbol(ent))
+		}
+
+		return nil
+	})
+
+	return syms, nil
+}
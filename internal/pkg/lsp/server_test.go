@@ -0,0 +1,222 @@
+// [# %stop! #]
+
+package lsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestServer(t *testing.T) {
+	root, err := ioutil.TempDir("", "clutter-lsp-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	if err := ioutil.WriteFile(filepath.Join(root, "b.txt"), []byte("[# x #]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	idx := index.NewIndex([]*index.Entry{
+		{Name: "x", Loc: scanner.Loc{Path: "b.txt", Line: 1, StartColumn: 1, EndColumn: 7}},
+	})
+
+	s, err := NewServer(zlog.NewNopLogger(), root, scanner.Config{
+		Bracket: scanner.BracketConfig{Left: "[#", Right: "#]"},
+	}, nil, idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		in     bytes.Buffer
+		nextID int
+	)
+
+	uri := s.pathToURI("a.go")
+
+	send := func(method string, params interface{}, notify bool) {
+		bs, err := json.Marshal(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg := &message{JSONRPC: "2.0", Method: method, Params: bs}
+
+		if !notify {
+			nextID++
+			id := json.RawMessage(fmt.Sprint(nextID))
+			msg.ID = &id
+		}
+
+		body, _ := json.Marshal(msg)
+
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+
+	pos := func(line, char int) TextDocumentPositionParams {
+		return TextDocumentPositionParams{
+			TextDocument: TextDocumentIdentifier{URI: uri},
+			Position:     Position{Line: line, Character: char},
+		}
+	}
+
+	send("initialize", &InitializeParams{}, false)
+	send("initialized", struct{}{}, true)
+	send("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{
+			URI:  uri,
+			Text: "// é [# x #]\n// [# y a=1 a=2 #]\n",
+		},
+	}, true)
+	send("textDocument/definition", pos(0, 6), false)
+	send("textDocument/references", pos(0, 6), false)
+	send("textDocument/hover", pos(0, 6), false)
+	send("textDocument/hover", pos(0, 1), false)
+	send("workspace/symbol", &WorkspaceSymbolParams{Query: "X"}, false)
+	send("textDocument/documentSymbol", &DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: uri}}, false)
+	send("no/such/method", struct{}{}, false)
+	send("shutdown", nil, false)
+	send("exit", nil, true)
+
+	var out bytes.Buffer
+
+	if err := s.Serve(context.Background(), &in, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		c         = newConn(&out, nil)
+		responses = map[string]*message{}
+		diags     []*PublishDiagnosticsParams
+	)
+
+	for {
+		msg, err := c.read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg.ID == nil {
+			var p PublishDiagnosticsParams
+			if err := json.Unmarshal(msg.Params, &p); err != nil {
+				t.Fatal(err)
+			}
+
+			diags = append(diags, &p)
+
+			continue
+		}
+
+		responses[string(*msg.ID)] = msg
+	}
+
+	result := func(id int, v interface{}) {
+		msg := responses[fmt.Sprint(id)]
+		if msg == nil {
+			t.Fatalf("no response for %d", id)
+		}
+
+		if msg.Error != nil {
+			t.Fatalf("response %d: %v", id, msg.Error)
+		}
+
+		bs, _ := json.Marshal(msg.Result)
+		if err := json.Unmarshal(bs, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(diags) != 1 || diags[0].URI != uri || len(diags[0].Diagnostics) != 1 {
+		t.Fatalf("unexpected diagnostics: %s", mustMarshal(diags))
+	}
+
+	if r := diags[0].Diagnostics[0].Range; r.Start.Line != 1 || r.Start.Character != 3 {
+		t.Errorf("unexpected diagnostic range: %+v", r)
+	}
+
+	// "é" is two bytes but a single UTF-16 unit.
+	atA := Location{URI: uri, Range: Range{Start: Position{0, 5}, End: Position{0, 12}}}
+	atB := Location{URI: s.pathToURI("b.txt"), Range: Range{Start: Position{0, 0}, End: Position{0, 7}}}
+
+	var locs []Location
+
+	result(2, &locs)
+
+	if exp := []Location{atB}; !reflect.DeepEqual(locs, exp) {
+		t.Errorf("definition: %+v != %+v", locs, exp)
+	}
+
+	result(3, &locs)
+
+	if exp := []Location{atA, atB}; !reflect.DeepEqual(locs, exp) {
+		t.Errorf("references: %+v != %+v", locs, exp)
+	}
+
+	var hover *Hover
+
+	result(4, &hover)
+
+	if hover == nil || !strings.HasPrefix(hover.Contents.Value, "**x** (2 in scope)") {
+		t.Errorf("unexpected hover: %+v", hover)
+	}
+
+	hover = nil
+
+	result(5, &hover)
+
+	if hover != nil {
+		t.Errorf("expected no hover outside of tags, got %+v", hover)
+	}
+
+	var syms []SymbolInformation
+
+	result(6, &syms)
+
+	if len(syms) != 2 {
+		t.Errorf("expected 2 workspace symbols, got %+v", syms)
+	}
+
+	result(7, &syms)
+
+	if len(syms) != 1 || syms[0].Name != "x" || syms[0].Location != atA {
+		t.Errorf("unexpected document symbols: %+v", syms)
+	}
+
+	if msg := responses["8"]; msg == nil || msg.Error == nil || msg.Error.Code != codeMethodNotFound {
+		t.Errorf("expected method not found, got %+v", msg)
+	}
+
+	if msg := responses["9"]; msg == nil || msg.Error != nil {
+		t.Errorf("unexpected shutdown response: %+v", msg)
+	}
+}
+
+func mustMarshal(v interface{}) string {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(bs)
+}
@@ -0,0 +1,130 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JUnit XML, as consumed by CI test reporters. Each rule is a test suite,
+// and each violation a failed test case in it. Rules that time out are test
+// cases with errors rather than failures.
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	File      string        `xml:"file,attr"`
+	Line      int           `xml:"line,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",cdata"`
+}
+
+// junitWriter writes a single JUnit report of all records when closed. Test
+// suites are listed in the order their rules first appear in.
+type junitWriter struct {
+	w      io.Writer
+	suites junitTestSuites
+	rules  map[string]*junitTestSuite
+}
+
+func newJUnitWriter(w io.Writer) *junitWriter {
+	return &junitWriter{
+		w:      w,
+		suites: junitTestSuites{Name: "clutter"},
+		rules:  make(map[string]*junitTestSuite),
+	}
+}
+
+func (j *junitWriter) Write(r *Record) error {
+	suite := j.rules[r.Rule]
+	if suite == nil {
+		suite = &junitTestSuite{Name: r.Rule}
+		j.rules[r.Rule] = suite
+		j.suites.Suites = append(j.suites.Suites, suite)
+	}
+
+	severity := r.Severity
+	if severity == "" {
+		severity = "error"
+	}
+
+	text := []string{r.Loc, r.Text()}
+	if r.Message != "" {
+		text = append(text, r.Message)
+	}
+
+	if r.Description != "" {
+		text = append(text, r.Description)
+	}
+
+	tc := junitTestCase{
+		ClassName: r.Path,
+		Name:      r.Loc,
+		File:      r.Path,
+		Line:      r.Line,
+	}
+
+	f := &junitFailure{
+		Type:    severity,
+		Message: fmt.Sprintf("%s: %s", r.Rule, r.Summary()),
+		Text:    strings.Join(text, "\n"),
+	}
+
+	suite.Tests++
+	j.suites.Tests++
+
+	if r.Kind == KindTimeout {
+		tc.Error = f
+		suite.Errors++
+		j.suites.Errors++
+	} else {
+		tc.Failure = f
+		suite.Failures++
+		j.suites.Failures++
+	}
+
+	suite.Cases = append(suite.Cases, tc)
+
+	return nil
+}
+
+func (j *junitWriter) Close() error {
+	if _, err := io.WriteString(j.w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(j.w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(j.suites); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(j.w, "\n")
+
+	return err
+}
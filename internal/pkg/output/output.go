@@ -0,0 +1,218 @@
+// Package output writes entries in the formats selectable using the
+// --output flag of commands that print entries.
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+)
+
+const (
+	FormatDefault  = ""
+	FormatJSON     = "json"
+	FormatJSONL    = "jsonl"
+	FormatQuickfix = "quickfix"
+	FormatSARIF    = "sarif"
+	FormatJUnit    = "junit"
+
+	// the rest of the format is the template text, for example:
+	// template={{.Name}} {{.Loc}}
+	templatePrefix = "template="
+)
+
+// Kinds of lint records.
+const (
+	KindViolation         = "violation"
+	KindTimeout           = "timeout" // the rule did not complete in time.
+	KindUnusedSuppression = "unused-suppression"
+)
+
+// Record is a single output item: an entry, and for lint, the rule it
+// violates. It is also the data passed to output templates.
+type Record struct {
+	Name      string            `json:"name"`
+	Path      string            `json:"path"`
+	Line      int               `json:"line"`
+	Column    int               `json:"column"`
+	EndLine   int               `json:"end_line"`
+	EndColumn int               `json:"end_column"`
+	Loc       string            `json:"loc"`
+	Attrs     map[string]string `json:"attrs"`
+	Rule      string            `json:"rule,omitempty"`
+
+	// For lint only.
+	Kind        string `json:"kind,omitempty"` // one of the Kind constants.
+	Severity    string `json:"severity,omitempty"`
+	Description string `json:"description,omitempty"`
+	Message     string `json:"message,omitempty"`
+
+	Entry *index.Entry `json:"-"`
+}
+
+func NewRecord(ent *index.Entry, rule string) *Record {
+	attrs := make(map[string]string, len(ent.Attrs))
+	for k, v := range ent.Attrs {
+		attrs[k] = v
+	}
+
+	return &Record{
+		Name:      ent.Name,
+		Path:      ent.Loc.Path,
+		Line:      ent.Loc.Line,
+		Column:    ent.Loc.StartColumn,
+		EndLine:   ent.Loc.LastLine(),
+		EndColumn: ent.Loc.EndColumn,
+		Loc:       ent.Loc.String(),
+		Attrs:     attrs,
+		Rule:      rule,
+		Entry:     ent,
+	}
+}
+
+// Text returns the name and sorted attributes of the entry, in the same
+// notation used in the index.
+func (r *Record) Text() string {
+	parts := []string{r.Name}
+
+	keys := make([]string, 0, len(r.Attrs))
+	for k := range r.Attrs {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		parts = append(parts, index.AttrToString(k, r.Attrs[k]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+type Writer interface {
+	Write(*Record) error
+
+	// Close must be called after all records are written. It does not close
+	// the underlying writer.
+	Close() error
+}
+
+// NewWriter returns a writer for format. Records are formatted using dflt
+// for FormatDefault.
+func NewWriter(w io.Writer, format string, dflt func(*Record) string) (Writer, error) {
+	switch format {
+	case FormatDefault:
+		return &textWriter{w: w, f: func(r *Record) (string, error) { return dflt(r), nil }}, nil
+	case FormatJSON:
+		return &jsonWriter{w: w, recs: []*Record{}}, nil
+	case FormatJSONL:
+		return &jsonWriter{w: w, lines: true}, nil
+	case FormatQuickfix:
+		return &textWriter{w: w, f: quickfix}, nil
+	case FormatSARIF:
+		return newSARIFWriter(w), nil
+	case FormatJUnit:
+		return newJUnitWriter(w), nil
+	}
+
+	if strings.HasPrefix(format, templatePrefix) {
+		tmpl, err := template.New("output").Parse(strings.TrimPrefix(format, templatePrefix))
+		if err != nil {
+			return nil, fmt.Errorf("template: %w", err)
+		}
+
+		return &textWriter{
+			w: w,
+			f: func(r *Record) (string, error) {
+				var b bytes.Buffer
+				if err := tmpl.Execute(&b, r); err != nil {
+					return "", fmt.Errorf("template: %w", err)
+				}
+
+				return b.String(), nil
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// IsReport returns true if format is a report of rule violations, which is
+// only meaningful for lint.
+func IsReport(format string) bool { return format == FormatSARIF || format == FormatJUnit }
+
+// Summary returns the message of a lint record if it has one, else Text.
+func (r *Record) Summary() string {
+	if r.Message != "" {
+		return r.Message
+	}
+
+	return r.Text()
+}
+
+// quickfix formats records like grep and compilers do, as understood by vim's
+// quickfix and most editors: path:line:col: text.
+func quickfix(r *Record) (string, error) {
+	text := r.Summary()
+	if r.Rule != "" {
+		text = fmt.Sprintf("%s: %s", r.Rule, text)
+	}
+
+	if r.Severity != "" {
+		text = fmt.Sprintf("%s: %s", r.Severity, text)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", r.Path, r.Line, r.Column, text), nil
+}
+
+type textWriter struct {
+	w io.Writer
+	f func(*Record) (string, error)
+}
+
+func (t *textWriter) Write(r *Record) error {
+	text, err := t.f(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(t.w, text)
+
+	return err
+}
+
+func (*textWriter) Close() error { return nil }
+
+// jsonWriter writes either a JSON object per line, or a single JSON array of
+// all records when closed.
+type jsonWriter struct {
+	w     io.Writer
+	lines bool
+	recs  []*Record
+}
+
+func (j *jsonWriter) Write(r *Record) error {
+	if !j.lines {
+		j.recs = append(j.recs, r)
+		return nil
+	}
+
+	return json.NewEncoder(j.w).Encode(r)
+}
+
+func (j *jsonWriter) Close() error {
+	if j.lines {
+		return nil
+	}
+
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(j.recs)
+}
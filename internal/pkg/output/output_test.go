@@ -0,0 +1,191 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+)
+
+func TestWriter(t *testing.T) {
+	ents := []*index.Entry{
+		{Name: "meow", Attrs: index.Attrs{"lang": "go", "see": ""}, Loc: scanner.Loc{Path: "a.go", Line: 1, StartColumn: 4, EndColumn: 20}},
+		{Name: "woof", Loc: scanner.Loc{Path: "b.go", Line: 2, StartColumn: 1, EndLine: 3, EndColumn: 5}},
+	}
+
+	tests := []struct {
+		format string
+		rule   string
+		exp    string
+		err    bool
+	}{
+		{
+			format: FormatDefault,
+			exp:    "meow\nwoof\n",
+		},
+		{
+			format: FormatJSONL,
+			exp: `{"name":"meow","path":"a.go","line":1,"column":4,"end_line":1,"end_column":20,"loc":"a.go:1.4-20","attrs":{"lang":"go","see":""}}` + "\n" +
+				`{"name":"woof","path":"b.go","line":2,"column":1,"end_line":3,"end_column":5,"loc":"b.go:2.1-3.5","attrs":{}}` + "\n",
+		},
+		{
+			format: FormatJSONL,
+			rule:   "r",
+			exp: `{"name":"meow","path":"a.go","line":1,"column":4,"end_line":1,"end_column":20,"loc":"a.go:1.4-20","attrs":{"lang":"go","see":""},"rule":"r"}` + "\n" +
+				`{"name":"woof","path":"b.go","line":2,"column":1,"end_line":3,"end_column":5,"loc":"b.go:2.1-3.5","attrs":{},"rule":"r"}` + "\n",
+		},
+		{
+			format: FormatQuickfix,
+			exp:    "a.go:1:4: meow lang=go see\nb.go:2:1: woof\n",
+		},
+		{
+			format: FormatQuickfix,
+			rule:   "r",
+			exp:    "a.go:1:4: r: meow lang=go see\nb.go:2:1: r: woof\n",
+		},
+		{
+			format: "template={{.Name}} {{.Loc}} {{.Attrs.lang}}",
+			exp:    "meow a.go:1.4-20 go\nwoof b.go:2.1-3.5 <no value>\n",
+		},
+		{
+			format: "template={{.Name",
+			err:    true,
+		},
+		{
+			format: "nosuchformat",
+			err:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var b bytes.Buffer
+
+			w, err := NewWriter(&b, test.format, func(r *Record) string { return r.Name })
+
+			if test.err {
+				if err == nil {
+					t.Errorf("error expected, but got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			for _, ent := range ents {
+				if err := w.Write(NewRecord(ent, test.rule)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if b.String() != test.exp {
+				t.Errorf("\n%s\n!=\n%s", b.String(), test.exp)
+			}
+		})
+	}
+}
+
+func TestReports(t *testing.T) {
+	ents := []*index.Entry{
+		{Name: "meow", Attrs: index.Attrs{"lang": "go"}, Loc: scanner.Loc{Path: "a.go", Line: 1, StartColumn: 4, EndColumn: 20}},
+		{Name: "woof", Loc: scanner.Loc{Path: "b.go", Line: 2, StartColumn: 1, EndLine: 3, EndColumn: 5}},
+	}
+
+	write := func(format string) string {
+		var b bytes.Buffer
+
+		w, err := NewWriter(&b, format, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, ent := range ents {
+			r := NewRecord(ent, "r")
+			if i == 1 {
+				r.Rule, r.Severity, r.Description, r.Kind = "s", "warning", "no woofs", KindTimeout
+			}
+
+			if err := w.Write(r); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		return b.String()
+	}
+
+	t.Run(FormatSARIF, func(t *testing.T) {
+		var log sarifLog
+		if err := json.Unmarshal([]byte(write(FormatSARIF)), &log); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(log.Runs) != 1 {
+			t.Fatalf("runs: %d != 1", len(log.Runs))
+		}
+
+		run := log.Runs[0]
+
+		exp := []sarifRule{
+			{ID: "r", ShortDescription: sarifMessage{Text: "r"}, DefaultConfiguration: sarifConfiguration{Level: "error"}},
+			{ID: "s", ShortDescription: sarifMessage{Text: "no woofs"}, DefaultConfiguration: sarifConfiguration{Level: "warning"}},
+		}
+
+		if !reflect.DeepEqual(run.Tool.Driver.Rules, exp) {
+			t.Errorf("rules: %+v != %+v", run.Tool.Driver.Rules, exp)
+		}
+
+		if len(run.Results) != 2 {
+			t.Fatalf("results: %d != 2", len(run.Results))
+		}
+
+		res := run.Results[1]
+
+		if res.RuleID != "s" || res.RuleIndex != 1 || res.Level != "warning" || res.Message.Text != "woof" {
+			t.Errorf("result: %+v", res)
+		}
+
+		expRegion := sarifRegion{StartLine: 2, StartColumn: 1, EndLine: 3, EndColumn: 6}
+
+		if loc := res.Locations[0].PhysicalLocation; loc.ArtifactLocation.URI != "b.go" || loc.Region != expRegion {
+			t.Errorf("location: %+v", loc)
+		}
+	})
+
+	t.Run(FormatJUnit, func(t *testing.T) {
+		exp := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="clutter" tests="2" failures="1" errors="1">
+  <testsuite name="r" tests="1" failures="1" errors="0">
+    <testcase classname="a.go" name="a.go:1.4-20" file="a.go" line="1">
+      <failure type="error" message="r: meow lang=go"><![CDATA[a.go:1.4-20
+meow lang=go]]></failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="s" tests="1" failures="0" errors="1">
+    <testcase classname="b.go" name="b.go:2.1-3.5" file="b.go" line="2">
+      <error type="warning" message="s: woof"><![CDATA[b.go:2.1-3.5
+woof
+no woofs]]></error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+
+		if got := write(FormatJUnit); got != exp {
+			t.Errorf("\n%s\n!=\n%s", got, exp)
+		}
+	})
+}
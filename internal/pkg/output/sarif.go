@@ -0,0 +1,165 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// SARIF 2.1.0, as consumed by code scanning tools. Only the fields clutter
+// fills are declared. See https://docs.oasis-open.org/sarif/sarif/v2.1.0/.
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"` // exclusive.
+}
+
+// sarifLevel maps a severity to a SARIF level: error, warning or note.
+func sarifLevel(severity string) string {
+	switch severity {
+	case "warning", "note":
+		return severity
+	case "info":
+		return "note"
+	}
+
+	return "error"
+}
+
+// sarifWriter writes a single SARIF log of all records when closed. Rules
+// are listed in the order they first appear in.
+type sarifWriter struct {
+	w     io.Writer
+	run   sarifRun
+	rules map[string]int
+}
+
+func newSARIFWriter(w io.Writer) *sarifWriter {
+	return &sarifWriter{
+		w: w,
+		run: sarifRun{
+			Tool: sarifTool{
+				Driver: sarifDriver{
+					Name:           "clutter",
+					InformationURI: "https://github.com/cluttercode/clutter",
+					Rules:          []sarifRule{},
+				},
+			},
+			Results: []sarifResult{},
+		},
+		rules: make(map[string]int),
+	}
+}
+
+func (s *sarifWriter) Write(r *Record) error {
+	level := sarifLevel(r.Severity)
+
+	ri, ok := s.rules[r.Rule]
+	if !ok {
+		desc := r.Description
+		if desc == "" {
+			desc = r.Rule
+		}
+
+		ri = len(s.run.Tool.Driver.Rules)
+		s.rules[r.Rule] = ri
+
+		s.run.Tool.Driver.Rules = append(s.run.Tool.Driver.Rules, sarifRule{
+			ID:                   r.Rule,
+			ShortDescription:     sarifMessage{Text: desc},
+			DefaultConfiguration: sarifConfiguration{Level: level},
+		})
+	}
+
+	s.run.Results = append(s.run.Results, sarifResult{
+		RuleID:    r.Rule,
+		RuleIndex: ri,
+		Level:     level,
+		Message:   sarifMessage{Text: r.Summary()},
+		Locations: []sarifLocation{
+			{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: r.Path, URIBaseID: "%SRCROOT%"},
+					Region: sarifRegion{
+						StartLine:   r.Line,
+						StartColumn: r.Column,
+						EndLine:     r.EndLine,
+						EndColumn:   r.EndColumn + 1,
+					},
+				},
+			},
+		},
+	})
+
+	return nil
+}
+
+func (s *sarifWriter) Close() error {
+	enc := json.NewEncoder(s.w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{s.run}})
+}
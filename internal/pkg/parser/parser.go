@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"text/scanner"
@@ -25,7 +26,26 @@ var (
 	validAttrNameRegexp = regexp.MustCompile(`^[\w_][\w_\:\-]*$`)
 )
 
+// IsValidName returns true if name can be used as a tag name.
+func IsValidName(name string) bool { return validNameRegexp.MatchString(name) }
+
+// IsValidAttrName returns true if name can be used as an attribute name.
+func IsValidAttrName(name string) bool { return validAttrNameRegexp.MatchString(name) }
+
 func ParseElement(elem *clutterScanner.RawElement) (*index.Entry, error) {
+	ent, _, _, err := parseElement(elem)
+	return ent, err
+}
+
+// NameSpan returns the byte offsets in elem.Text of the name of the tag,
+// excluding its sugar (a leading "." or "./"), but including its quotes if
+// it is quoted.
+func NameSpan(elem *clutterScanner.RawElement) (start, end int, err error) {
+	_, start, end, err = parseElement(elem)
+	return
+}
+
+func parseElement(elem *clutterScanner.RawElement) (_ *index.Entry, nameStart, nameEnd int, _ error) {
 	var s scanner.Scanner
 	s.Init(strings.NewReader(elem.Text))
 	s.Mode = scanner.ScanIdents | scanner.ScanStrings | scanner.ScanRawStrings
@@ -69,6 +89,8 @@ func ParseElement(elem *clutterScanner.RawElement) (*index.Entry, error) {
 				search = "glob"
 			case "e", "re":
 				search = "regexp"
+			case "q", "query":
+				search = "query"
 			default:
 				return fmt.Errorf("invalid search type: %q", v)
 			}
@@ -88,7 +110,7 @@ func ParseElement(elem *clutterScanner.RawElement) (*index.Entry, error) {
 				return addAttr(k, "")
 			}
 
-			if tok[0] == '"' {
+			if tok[0] == '"' || tok[0] == '`' {
 				var err error
 				tok, err = strconv.Unquote(tok)
 				if err != nil {
@@ -135,6 +157,8 @@ func ParseElement(elem *clutterScanner.RawElement) (*index.Entry, error) {
 			return fmt.Errorf("empty element")
 		}
 
+		nameStart, nameEnd = s.Position.Offset, s.Position.Offset+len(tok)
+
 		if tok[0] == '?' {
 			return addAttr("search", tok[1:])
 		}
@@ -150,15 +174,17 @@ func ParseElement(elem *clutterScanner.RawElement) (*index.Entry, error) {
 			}
 
 			tok = tok[2:]
+			nameStart += 2
 		} else if tok[0] == '.' {
 			if err := addAttr("scope", "."); err != nil {
 				return err
 			}
 
 			tok = tok[1:]
+			nameStart++
 		}
 
-		if tok[0] == '"' {
+		if tok[0] == '"' || tok[0] == '`' {
 			tok, err = strconv.Unquote(tok)
 			if err != nil {
 				return fmt.Errorf("invalid quotes: %w", err)
@@ -203,23 +229,46 @@ func ParseElement(elem *clutterScanner.RawElement) (*index.Entry, error) {
 
 	for tok := s.Scan(); tok != scanner.EOF; tok = s.Scan() {
 		if err != nil {
-			return nil, err
+			return nil, 0, 0, err
 		}
 
 		if err = state(s.TokenText(), false); err != nil {
-			return nil, err
+			return nil, 0, 0, err
 		}
 	}
 
 	if err = state("", true); err != nil {
-		return nil, err
+		return nil, 0, 0, err
 	}
 
+	ent.TakeNoLint()
+	ent.NoLint = addNoLint(ent.NoLint, elem.NoLint)
+	ent.NoLintFile = addNoLint(ent.NoLintFile, elem.NoLintFile)
+
 	if len(ent.Attrs) == 0 {
 		ent.Attrs = nil
 	}
 
-	return &ent, nil
+	return &ent, nameStart, nameEnd, nil
+}
+
+// addNoLint adds rules suppressed by pragmas to those already suppressed by
+// the tag's attributes, if any, see [# nolint-pragmas #].
+func addNoLint(all, rules []string) []string {
+	for _, r := range rules {
+		dup := false
+		for _, a := range all {
+			if dup = a == r; dup {
+				break
+			}
+		}
+
+		if !dup {
+			all = append(all, r)
+		}
+	}
+
+	return all
 }
 
 func ParseElements(elems []*clutterScanner.RawElement) ([]*index.Entry, error) {
@@ -234,3 +283,72 @@ func ParseElements(elems []*clutterScanner.RawElement) ([]*index.Entry, error) {
 
 	return ents, nil
 }
+
+// searchSugar maps search types to the sugar that denotes them.
+var searchSugar = map[string]string{
+	exact:    "?",
+	"glob":   "?g",
+	"regexp": "?re",
+	"query":  "?q",
+}
+
+// FormatElement returns the text of a tag, without brackets, that parses
+// back into ent. Scopes of the tag's own file or directory are written using
+// sugar, and attributes are sorted.
+func FormatElement(ent *index.Entry) string {
+	var parts []string
+
+	if search, ok := searchSugar[ent.Attrs["search"]]; ok {
+		parts = append(parts, search)
+	}
+
+	name := quoteIfNeeded(ent.Name)
+
+	scope, hasScope := ent.Attrs["scope"]
+
+	if hasScope && name == ent.Name {
+		if scope == ent.Loc.Path {
+			name, hasScope = "."+name, false
+		} else if dir := filepath.Dir(ent.Loc.Path); dir != "." && scope == dir+"/" {
+			name, hasScope = "./"+name, false
+		}
+	}
+
+	parts = append(parts, name)
+
+	attrs := ent.AttrsWithNoLint()
+
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		if k != "search" && (k != "scope" || hasScope) {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if v := attrs[k]; v != "" {
+			parts = append(parts, fmt.Sprintf("%s=%s", k, quoteIfNeeded(v)))
+		} else {
+			parts = append(parts, k)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// quoteIfNeeded quotes s unless it is scanned as a single identifier.
+func quoteIfNeeded(s string) string {
+	for i, r := range s {
+		if !unicode.IsLetter(r) && (i == 0 || !(unicode.IsDigit(r) || strings.ContainsRune("-_:/", r))) {
+			return strconv.Quote(s)
+		}
+	}
+
+	if s == "" {
+		return strconv.Quote(s)
+	}
+
+	return s
+}
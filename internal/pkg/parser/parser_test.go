@@ -162,6 +162,14 @@ func TestParseElement(t *testing.T) {
 			},
 			search: true,
 		},
+		{
+			text: "?q `name~\"^api-\" and not deprecated`",
+			name: "name~\"^api-\" and not deprecated",
+			attrs: map[string]string{
+				"search": "query",
+			},
+			search: true,
+		},
 		{
 			text: "?g bla x=\"any*\"",
 			name: "bla",
@@ -233,3 +241,102 @@ func TestParseElement(t *testing.T) {
 		})
 	}
 }
+
+func TestNameSpan(t *testing.T) {
+	tests := []struct {
+		text  string
+		start int
+		span  string
+	}{
+		{text: "meow", start: 0, span: "meow"},
+		{text: ".meow", start: 1, span: "meow"},
+		{text: "./meow", start: 2, span: "meow"},
+		{text: `"meow"`, start: 0, span: `"meow"`},
+		{text: "@who=zumi meow when=now", start: 10, span: "meow"},
+		{text: "@who meow", start: 5, span: "meow"},
+		{text: "@owner=meow .meow who=meow", start: 13, span: "meow"},
+		{text: `?re "meow.*"`, start: 4, span: `"meow.*"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			start, end, err := NameSpan(&scanner.RawElement{Text: test.text})
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			if start != test.start {
+				t.Errorf("start: %d != %d", start, test.start)
+			}
+
+			if span := test.text[start:end]; span != test.span {
+				t.Errorf("span: %q != %q", span, test.span)
+			}
+		})
+	}
+}
+
+func TestParseElementNoLint(t *testing.T) {
+	ent, err := ParseElement(
+		&scanner.RawElement{
+			Text:       "meow nolint=\"a,b\"",
+			Loc:        scanner.Loc{Path: "dir/file"},
+			NoLint:     []string{"b", "c"},
+			NoLintFile: []string{"d"},
+		},
+	)
+	if err != nil {
+		t.Fatalf("got error: %v", err)
+	}
+
+	if exp := []string{"a", "b", "c"}; !reflect.DeepEqual(ent.NoLint, exp) {
+		t.Errorf("nolint: %v != %v", ent.NoLint, exp)
+	}
+
+	if exp := []string{"d"}; !reflect.DeepEqual(ent.NoLintFile, exp) {
+		t.Errorf("nolint-file: %v != %v", ent.NoLintFile, exp)
+	}
+
+	if len(ent.Attrs) != 0 {
+		t.Errorf("suppressions in attributes: %v", ent.Attrs)
+	}
+}
+
+func TestFormatElement(t *testing.T) {
+	tests := []struct {
+		text, exp string
+	}{
+		{text: "meow", exp: "meow"},
+		{text: "meow b=x a", exp: "meow a b=x"},
+		{text: "@who=midnight .meow", exp: ".meow who=midnight"},
+		{text: "./meow", exp: "./meow"},
+		{text: "meow scope=other/", exp: "meow scope=other/"},
+		{text: `"me.ow" scope=.`, exp: `"me.ow" scope=dir/file`},
+		{text: `meow when="now and then" n="12"`, exp: `meow n="12" when="now and then"`},
+		{text: "?g \"*\" x=\"any*\"", exp: "?g \"*\" x=\"any*\""},
+		{text: "?q `name~\"^api-\"`", exp: `?q "name~\"^api-\""`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			ent, err := ParseElement(&scanner.RawElement{Text: test.text, Loc: scanner.Loc{Path: "dir/file"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			text := FormatElement(ent)
+			if text != test.exp {
+				t.Errorf("%q != %q", text, test.exp)
+			}
+
+			back, err := ParseElement(&scanner.RawElement{Text: text, Loc: ent.Loc})
+			if err != nil {
+				t.Fatalf("parse back: %v", err)
+			}
+
+			if !reflect.DeepEqual(back, ent) {
+				t.Errorf("parse back: %v != %v", back, ent)
+			}
+		})
+	}
+}
@@ -0,0 +1,375 @@
+// Package renamer renames and rewrites tags in place, in the files they
+// appear in.
+package renamer
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/parser"
+	"github.com/cluttercode/clutter/internal/pkg/resolver"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+// Change is the content of a file before and after renaming.
+type Change struct {
+	Path     string
+	Old, New []byte
+}
+
+// Apply writes the new content to the file, keeping its permissions.
+func (c *Change) Apply() error {
+	fi, err := os.Stat(c.Path)
+	if err != nil {
+		return err // do not wrap
+	}
+
+	return ioutil.WriteFile(c.Path, c.New, fi.Mode().Perm())
+}
+
+// TargetsAt returns all tags that refer to the same concept as what,
+// according to the resolver's scope rules. Search tags are patterns rather
+// than references, and are never renamed.
+func TargetsAt(z *zlog.Logger, idx *index.Index, what *index.Entry) ([]*index.Entry, error) {
+	if _, search := what.IsSearch(); search {
+		return nil, fmt.Errorf("search tags cannot be renamed")
+	}
+
+	ents, err := resolver.ResolveList(z, what, idx)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := ents[:0]
+
+	for _, ent := range ents {
+		if _, search := ent.IsSearch(); !search {
+			targets = append(targets, ent)
+		}
+	}
+
+	return targets, nil
+}
+
+// TargetsNamed returns all tags named name, in all scopes.
+func TargetsNamed(idx *index.Index, name string) []*index.Entry {
+	var ents []*index.Entry
+
+	_ = index.ForEach(idx, func(ent *index.Entry) error {
+		if _, search := ent.IsSearch(); !search && ent.Name == name {
+			ents = append(ents, ent)
+		}
+
+		return nil
+	})
+
+	return ents
+}
+
+// Check returns an error if ents cannot be renamed to name: if name is not
+// a valid name, or if a tag in idx that is not renamed is already named name
+// in the scope of any of the renamed tags.
+func Check(idx *index.Index, ents []*index.Entry, name string) error {
+	if !parser.IsValidName(name) {
+		return fmt.Errorf("invalid name: %q", name)
+	}
+
+	renamed := make(map[scanner.Loc]bool, len(ents))
+	for _, ent := range ents {
+		renamed[ent.Loc] = true
+	}
+
+	for _, ent := range ents {
+		after := *ent
+		after.Name = name
+
+		var collision *index.Entry
+
+		_ = index.ForEach(idx, func(other *index.Entry) error {
+			if _, search := other.IsSearch(); search || other.Name != name || renamed[other.Loc] {
+				return nil
+			}
+
+			if other.IsReferredBy(&after) || after.IsReferredBy(other) {
+				collision = other
+				return index.ErrStop
+			}
+
+			return nil
+		})
+
+		if collision != nil {
+			return fmt.Errorf("%v: %q is already used in scope at %v", ent.Loc, name, collision.Loc)
+		}
+	}
+
+	return nil
+}
+
+// Rename returns the changes to the files containing ents that rename them
+// to name. Only the name of each tag is rewritten. Its sugar, quotes, and
+// attributes are kept as they are. Files are read using readFile and
+// rescanned using cfg, to make sure the tags are still where ents say they
+// are.
+func Rename(z *zlog.Logger, cfg scanner.Config, ents []*index.Entry, name string, readFile func(string) ([]byte, error)) ([]*Change, error) {
+	return editFiles(z, cfg, ents, readFile, func(bs []byte, _ *index.Entry, elem *scanner.RawElement) (*edit, error) {
+		return renameElement(cfg.Bracket, bs, elem, name)
+	})
+}
+
+// Rewrite returns the changes to the files containing ents that replace the
+// text of each tag between its brackets with the text returned by f for it.
+// f is given the element of the tag as rescanned from its file, and returns
+// an empty string to keep the tag as it is. Files without changes are
+// omitted. See Rename for readFile.
+func Rewrite(z *zlog.Logger, cfg scanner.Config, ents []*index.Entry, f func(*index.Entry, *scanner.RawElement) (string, error), readFile func(string) ([]byte, error)) ([]*Change, error) {
+	all, err := editFiles(z, cfg, ents, readFile, func(bs []byte, ent *index.Entry, elem *scanner.RawElement) (*edit, error) {
+		text, err := f(ent, elem)
+		if err != nil || text == "" {
+			return nil, err
+		}
+
+		return rewriteElement(cfg.Bracket, bs, elem, text)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*Change
+
+	for _, c := range all {
+		if !bytes.Equal(c.Old, c.New) {
+			changes = append(changes, c)
+		}
+	}
+
+	return changes, nil
+}
+
+// editFiles returns the changes to the files containing ents, in order of
+// their paths, that apply the edits returned by f, as in editFile. f is
+// given the content of the file.
+func editFiles(z *zlog.Logger, cfg scanner.Config, ents []*index.Entry, readFile func(string) ([]byte, error), f func([]byte, *index.Entry, *scanner.RawElement) (*edit, error)) ([]*Change, error) {
+	byPath := make(map[string][]*index.Entry)
+	for _, ent := range ents {
+		byPath[ent.Loc.Path] = append(byPath[ent.Loc.Path], ent)
+	}
+
+	paths := make([]string, 0, len(byPath))
+	for path := range byPath {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	changes := make([]*Change, 0, len(paths))
+
+	for _, path := range paths {
+		bs, err := readFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		out, err := editFile(z.With("path", path), cfg, path, bs, byPath[path], func(ent *index.Entry, elem *scanner.RawElement) (*edit, error) {
+			return f(bs, ent, elem)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, &Change{Path: path, Old: bs, New: out})
+	}
+
+	return changes, nil
+}
+
+type edit struct {
+	start, end int
+	text       string
+}
+
+// editFile applies the edits returned by f for the elements of ents in bs. f
+// returns a nil edit to leave an element as it is.
+func editFile(z *zlog.Logger, cfg scanner.Config, path string, bs []byte, ents []*index.Entry, f func(*index.Entry, *scanner.RawElement) (*edit, error)) ([]byte, error) {
+	elems := make(map[scanner.Loc]*scanner.RawElement)
+
+	if err := scanner.ScanReader(z, cfg, path, bytes.NewReader(bs), func(elem *scanner.RawElement) error {
+		elems[elem.Loc] = elem
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("%s: scan: %w", path, err)
+	}
+
+	edits := make([]edit, 0, len(ents))
+
+	for _, ent := range ents {
+		elem := elems[ent.Loc]
+		if elem == nil {
+			return nil, fmt.Errorf("%v: tag not found, index might be out of date", ent.Loc)
+		}
+
+		e, err := f(ent, elem)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", ent.Loc, err)
+		}
+
+		if e == nil {
+			continue
+		}
+
+		z.Debugw("edit", "loc", ent.Loc, "edit", e)
+
+		edits = append(edits, *e)
+	}
+
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+
+	out := append([]byte(nil), bs...)
+
+	for _, e := range edits {
+		out = append(out[:e.start], append([]byte(e.text), out[e.end:]...)...)
+	}
+
+	return out, nil
+}
+
+// renameElement finds the name of elem in the file content bs. The element
+// text is derived from the file by stripping brackets and, for multi-line
+// tags, comment leaders and line breaks, none of which can be part of a name.
+// Hence the name is found in the file as the same occurrence of it in the
+// element text.
+func renameElement(bracket scanner.BracketConfig, bs []byte, elem *scanner.RawElement, name string) (*edit, error) {
+	start, end, err := parser.NameSpan(elem)
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+
+	old := elem.Text[start:end]
+
+	text := name
+
+	// names with runes that are not allowed in unquoted names, like ".",
+	// must be quoted.
+	if old[0] == '"' || strings.IndexFunc(name, func(r rune) bool { return !isNameRune(r) }) >= 0 {
+		if start > 0 && strings.ContainsRune("./", rune(elem.Text[start-1])) {
+			return nil, fmt.Errorf("%q must be quoted, which is not supported with scope sugar", name)
+		}
+
+		text = strconv.Quote(name)
+	} else if old[0] == '`' {
+		text = "`" + name + "`"
+	}
+
+	nth := 0
+	for _, i := range occurrences(elem.Text, old) {
+		if i >= start {
+			break
+		}
+
+		nth++
+	}
+
+	spanStart, spanEnd, err := locSpan(bs, elem.Loc)
+	if err != nil {
+		return nil, err
+	}
+
+	// brackets are excluded, as they are not in the element text.
+	spanStart += len(bracket.Left)
+	spanEnd -= len(bracket.Right)
+
+	if spanStart > spanEnd {
+		return nil, fmt.Errorf("tag text mismatch")
+	}
+
+	is := occurrences(string(bs[spanStart:spanEnd]), old)
+	if nth >= len(is) {
+		return nil, fmt.Errorf("name %q not found in tag", old)
+	}
+
+	offset := spanStart + is[nth]
+
+	return &edit{start: offset, end: offset + len(old), text: text}, nil
+}
+
+// rewriteElement replaces all text of elem between its brackets with text,
+// which is padded with a space on either side.
+func rewriteElement(bracket scanner.BracketConfig, bs []byte, elem *scanner.RawElement, text string) (*edit, error) {
+	start, end, err := locSpan(bs, elem.Loc)
+	if err != nil {
+		return nil, err
+	}
+
+	start += len(bracket.Left)
+	end -= len(bracket.Right)
+
+	if start > end {
+		return nil, fmt.Errorf("tag text mismatch")
+	}
+
+	return &edit{start: start, end: end, text: " " + text + " "}, nil
+}
+
+// locSpan returns the byte offsets in bs of the text at loc.
+func locSpan(bs []byte, loc scanner.Loc) (start, end int, err error) {
+	lineOffset := func(n int) (int, error) {
+		offset := 0
+
+		for i := 1; i < n; i++ {
+			nl := bytes.IndexByte(bs[offset:], '\n')
+			if nl < 0 {
+				return 0, fmt.Errorf("line %d out of range", n)
+			}
+
+			offset += nl + 1
+		}
+
+		return offset, nil
+	}
+
+	if start, err = lineOffset(loc.Line); err != nil {
+		return
+	}
+
+	if end, err = lineOffset(loc.LastLine()); err != nil {
+		return
+	}
+
+	start += loc.StartColumn - 1
+	end += loc.EndColumn
+
+	if start < 0 || end > len(bs) || start > end {
+		return 0, 0, fmt.Errorf("loc out of range")
+	}
+
+	return
+}
+
+// occurrences returns the offsets of all occurrences of s in text.
+func occurrences(text, s string) []int {
+	var is []int
+
+	for offset := 0; ; offset++ {
+		i := strings.Index(text[offset:], s)
+		if i < 0 {
+			return is
+		}
+
+		offset += i
+
+		is = append(is, offset)
+	}
+}
+
+func isNameRune(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_:/", r))
+}
@@ -0,0 +1,206 @@
+// [# %stop! #]
+
+package renamer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/parser"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestRename(t *testing.T) {
+	cfg := scanner.Config{Bracket: scanner.BracketConfig{Left: "[#", Right: "#]"}}
+
+	files := map[string]string{
+		"a.go": "// [# old #] and [# .old #]\n" +
+			"// [# @owner=old ./old x=1 #]\n" +
+			"// [# @who old #] [# \"old\" #] [# ? old #]\n",
+		"b.py": "# [# old y=2\n#   z=3 #] [# other #]\n",
+	}
+
+	readFile := func(path string) ([]byte, error) {
+		text, ok := files[path]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+
+		return []byte(text), nil
+	}
+
+	var elems []*scanner.RawElement
+
+	for _, path := range []string{"a.go", "b.py"} {
+		if err := scanner.ScanReader(zlog.NewNopLogger(), cfg, path, bytes.NewReader([]byte(files[path])), func(elem *scanner.RawElement) error {
+			elems = append(elems, elem)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ents, err := parser.ParseElements(elems)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idx := index.NewIndex(ents)
+
+	at := func(path string, line, col int) *index.Entry {
+		loc := scanner.Loc{Path: path, Line: line, StartColumn: col, EndColumn: col}
+
+		for _, ent := range ents {
+			if ent.Loc.Contains(loc) {
+				return ent
+			}
+		}
+
+		t.Fatalf("no tag at %v", loc)
+
+		return nil
+	}
+
+	tests := []struct {
+		name    string
+		targets func() ([]*index.Entry, error)
+		newName string
+		exp     map[string]string
+		err     bool
+	}{
+		{
+			name:    "by name",
+			targets: func() ([]*index.Entry, error) { return TargetsNamed(idx, "old"), nil },
+			newName: "new",
+			exp: map[string]string{
+				"a.go": "// [# new #] and [# .new #]\n" +
+					"// [# @owner=old ./new x=1 #]\n" +
+					"// [# @who new #] [# \"new\" #] [# ? old #]\n",
+				"b.py": "# [# new y=2\n#   z=3 #] [# other #]\n",
+			},
+		},
+		{
+			name:    "at loc with file scope",
+			targets: func() ([]*index.Entry, error) { return TargetsAt(zlog.NewNopLogger(), idx, at("a.go", 1, 20)) },
+			newName: "new",
+			exp: map[string]string{
+				"a.go": "// [# new #] and [# .new #]\n" +
+					"// [# @owner=old ./new x=1 #]\n" +
+					"// [# @who new #] [# \"new\" #] [# ? old #]\n",
+			},
+		},
+		{
+			name:    "collision",
+			targets: func() ([]*index.Entry, error) { return TargetsNamed(idx, "old"), nil },
+			newName: "other",
+			err:     true,
+		},
+		{
+			name:    "invalid name",
+			targets: func() ([]*index.Entry, error) { return TargetsNamed(idx, "old"), nil },
+			newName: "not valid",
+			err:     true,
+		},
+		{
+			name:    "search tag",
+			targets: func() ([]*index.Entry, error) { return TargetsAt(zlog.NewNopLogger(), idx, at("a.go", 3, 36)) },
+			newName: "new",
+			err:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			targets, err := test.targets()
+
+			if err == nil {
+				err = Check(idx, targets, test.newName)
+			}
+
+			var changes []*Change
+
+			if err == nil {
+				changes, err = Rename(zlog.NewNopLogger(), cfg, targets, test.newName, readFile)
+			}
+
+			if test.err {
+				if err == nil {
+					t.Errorf("error expected, but got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			got := make(map[string]string, len(changes))
+			for _, ch := range changes {
+				got[ch.Path] = string(ch.New)
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(test.exp) {
+				t.Errorf("\n%q\n!=\n%q", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	cfg := scanner.Config{Bracket: scanner.BracketConfig{Left: "[#", Right: "#]"}}
+
+	files := map[string]string{
+		"a.go": "// [# a #] and [#b x=1#]\n",
+		"b.py": "# [# a y=2\n#   z=3 #] [# c #]\n",
+		"c.sh": "# [# c #]\n",
+	}
+
+	readFile := func(path string) ([]byte, error) { return []byte(files[path]), nil }
+
+	var elems []*scanner.RawElement
+
+	for _, path := range []string{"a.go", "b.py", "c.sh"} {
+		if err := scanner.ScanReader(zlog.NewNopLogger(), cfg, path, bytes.NewReader([]byte(files[path])), func(elem *scanner.RawElement) error {
+			elems = append(elems, elem)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ents, err := parser.ParseElements(elems)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Rewrite(zlog.NewNopLogger(), cfg, ents, func(ent *index.Entry, elem *scanner.RawElement) (string, error) {
+		if ent.Name == "c" {
+			return "", nil
+		}
+
+		return elem.Text + " fixed", nil
+	}, readFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string, len(changes))
+	for _, ch := range changes {
+		got[ch.Path] = string(ch.New)
+	}
+
+	exp := map[string]string{
+		"a.go": "// [# a fixed #] and [# b x=1 fixed #]\n",
+		"b.py": "# [# a y=2 z=3 fixed #] [# c #]\n",
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Errorf("\n%q\n!=\n%q", got, exp)
+	}
+}
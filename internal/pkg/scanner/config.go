@@ -1,6 +1,8 @@
 package scanner
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"regexp"
 )
@@ -26,7 +28,55 @@ func (c *BracketConfig) Regexp() (*regexp.Regexp, error) {
 	)
 }
 
+const (
+	ModeRaw      = "raw"      // tags are scanned anywhere in a file.
+	ModeComments = "comments" // tags are scanned only in comments, by file language.
+)
+
+const (
+	SourceWalk = "walk" // files are found by walking the file system.
+	SourceGit  = "git"  // files are taken from the git index.
+)
+
 type Config struct {
-	Bracket BracketConfig `yaml:"bracket"`
-	Ignore  []string      `yaml:"ignore"`
+	Bracket   BracketConfig `yaml:"bracket"`
+	Ignore    []string      `yaml:"ignore"`
+	Mode      string        `yaml:"mode"`
+	Workers   int           `yaml:"workers"`   // 0 means number of CPUs.
+	GitIgnore bool          `yaml:"gitignore"` // also ignore files ignored by git.
+	Source    string        `yaml:"source"`
+}
+
+func (c *Config) Validate() error {
+	switch c.Mode {
+	case "", ModeRaw, ModeComments:
+	default:
+		return fmt.Errorf("invalid mode: %q", c.Mode)
+	}
+
+	switch c.Source {
+	case "", SourceWalk, SourceGit:
+	default:
+		return fmt.Errorf("invalid source: %q", c.Source)
+	}
+
+	if c.Workers < 0 {
+		return fmt.Errorf("invalid workers count: %d", c.Workers)
+	}
+
+	return nil
+}
+
+// Fingerprint identifies the settings of c that determine which tags are
+// found in a file. Files scanned with configs of different fingerprints may
+// have different tags.
+func (c *Config) Fingerprint() string {
+	mode := c.Mode
+	if mode == "" {
+		mode = ModeRaw
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s", mode, c.Bracket.Left, c.Bracket.Right)))
+
+	return hex.EncodeToString(sum[:8])
 }
@@ -3,4 +3,8 @@ package scanner
 type RawElement struct {
 	Text string
 	Loc  Loc
+
+	// Names of lint rules suppressed for this element by %nolint and
+	// %nolint-file pragmas.
+	NoLint, NoLintFile []string
 }
@@ -0,0 +1,32 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileError is an error that occurred while scanning a specific file.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string { return fmt.Sprintf("file %s: %v", e.Path, e.Err) }
+
+func (e *FileError) Unwrap() error { return e.Err }
+
+// FileErrors aggregates all errors that occurred while scanning a tree.
+type FileErrors []*FileError
+
+func (es FileErrors) Error() string {
+	if len(es) == 1 {
+		return es[0].Error()
+	}
+
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+
+	return fmt.Sprintf("%d files failed:\n%s", len(es), strings.Join(msgs, "\n"))
+}
@@ -1,21 +1,27 @@
 package scanner
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/cluttercode/clutter/pkg/gitignore"
 	"github.com/cluttercode/clutter/pkg/zlog"
 )
 
-var defaultIgnores = []string{
+// DefaultIgnores are used if Config.Ignore is empty.
+var DefaultIgnores = []string{
 	".git",
 }
 
-func NewFilter(z *zlog.Logger, cfg Config) (func(string, os.FileInfo) (bool, error), error) {
+// NewFilter returns a function that reports whether the file at path, which
+// is relative to root, should be scanned, or filepath.SkipDir for excluded
+// directories.
+func NewFilter(z *zlog.Logger, cfg Config, root string) (func(string, os.FileInfo) (bool, error), error) {
 	if len(cfg.Ignore) == 0 {
-		cfg.Ignore = defaultIgnores
+		cfg.Ignore = DefaultIgnores
 	}
 
 	ignores := make([]gitignore.Pattern, len(cfg.Ignore))
@@ -26,6 +32,19 @@ func NewFilter(z *zlog.Logger, cfg Config) (func(string, os.FileInfo) (bool, err
 
 	exclude := gitignore.NewMatcher(ignores).Match
 
+	if cfg.GitIgnore {
+		gi, err := newGitIgnores(z, root)
+		if err != nil {
+			return nil, fmt.Errorf("gitignore: %w", err)
+		}
+
+		excludeCfg := exclude
+
+		exclude = func(path []string, isDir bool) bool {
+			return excludeCfg(path, isDir) || gi.match(path, isDir)
+		}
+	}
+
 	return func(path string, fi os.FileInfo) (bool, error) {
 		var (
 			isDir, isLink bool
@@ -63,3 +82,109 @@ func NewFilter(z *zlog.Logger, cfg Config) (func(string, os.FileInfo) (bool, err
 		return !isDir, nil
 	}, nil
 }
+
+// gitIgnores matches paths against all git ignore files that apply to them:
+// the global excludes file, .git/info/exclude, and all .gitignore files from
+// the repository's root down to the path's directory. .gitignore files are
+// read lazily, the first time a path under their directory is matched.
+type gitIgnores struct {
+	z      *zlog.Logger
+	root   string              // of the repository.
+	prefix []string            // of matched paths, relative to root.
+	base   []gitignore.Pattern // global and repo patterns.
+
+	l    sync.Mutex
+	dirs map[string][]gitignore.Pattern
+}
+
+// newGitIgnores matches paths relative to dir, which may be anywhere in the
+// repository. If dir is not in a repository, it is treated as the root of one.
+func newGitIgnores(z *zlog.Logger, dir string) (*gitIgnores, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	root := findRepoRoot(dir)
+
+	var prefix []string
+	if rel, err := filepath.Rel(root, dir); err != nil {
+		return nil, err
+	} else if rel != "." {
+		prefix = strings.Split(filepath.ToSlash(rel), "/")
+	}
+
+	z.Debugw("git ignores", "root", root, "prefix", prefix)
+
+	global, err := gitignore.LoadGlobalPatterns()
+	if err != nil {
+		return nil, fmt.Errorf("global: %w", err)
+	}
+
+	repo, err := gitignore.ReadRepoPatterns(root)
+	if err != nil {
+		return nil, fmt.Errorf("repo: %w", err)
+	}
+
+	return &gitIgnores{
+		z:      z,
+		root:   root,
+		prefix: prefix,
+		base:   append(global, repo...),
+		dirs:   make(map[string][]gitignore.Pattern),
+	}, nil
+}
+
+// findRepoRoot returns the closest directory to the absolute dir, which is
+// dir or one of its parents, that has a .git entry, or dir if there is none.
+func findRepoRoot(dir string) string {
+	for d := dir; ; {
+		if _, err := os.Lstat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+
+		d = parent
+	}
+}
+
+func (g *gitIgnores) dirPatterns(dir []string) []gitignore.Pattern {
+	key := strings.Join(dir, "/")
+
+	g.l.Lock()
+	defer g.l.Unlock()
+
+	ps, ok := g.dirs[key]
+	if !ok {
+		var err error
+		if ps, err = gitignore.ReadDirPatterns(g.root, dir); err != nil {
+			// unreadable ignore files are ignored, like git does.
+			g.z.Warnw("cannot read .gitignore", "dir", key, "err", err)
+		}
+
+		g.dirs[key] = ps
+	}
+
+	return ps
+}
+
+func (g *gitIgnores) match(path []string, isDir bool) bool {
+	path = append(g.prefix[:len(g.prefix):len(g.prefix)], path...)
+
+	path = strings.Split(filepath.ToSlash(filepath.Clean(filepath.Join(path...))), "/")
+	if (len(path) == 1 && path[0] == ".") || path[0] == ".." {
+		return false
+	}
+
+	// patterns in increasing priority.
+	ps := g.base[:len(g.base):len(g.base)]
+	for i := 0; i < len(path); i++ {
+		ps = append(ps, g.dirPatterns(path[:i])...)
+	}
+
+	return gitignore.NewMatcher(ps).Match(path, isDir)
+}
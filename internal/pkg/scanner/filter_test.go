@@ -0,0 +1,108 @@
+package scanner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestGitIgnores(t *testing.T) {
+	root, err := ioutil.TempDir("", "clutter-filter-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	files := map[string]string{
+		"home/.gitconfig":        "[core]\n\texcludesFile = ~/global-ignore\n",
+		"home/global-ignore":     "*.global\n",
+		"repo/.git/info/exclude": "*.exclude\n",
+		"repo/.gitignore":        "node_modules/\n*.log\n!keep.log\n",
+		"repo/a/.gitignore":      "/gen\n# comment\n*.tmp\n",
+		"repo/a/b/.gitignore":    "!x.tmp\n",
+	}
+
+	for path, text := range files {
+		path = filepath.Join(root, filepath.FromSlash(path))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", filepath.Join(root, "home"))
+
+	gi, err := newGitIgnores(zlog.NewNopLogger(), filepath.Join(root, "repo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		excluded bool
+	}{
+		{path: "main.go"},
+		{path: "x.global", excluded: true},
+		{path: "a/x.exclude", excluded: true},
+		{path: "node_modules", isDir: true, excluded: true},
+		{path: "a/node_modules", isDir: true, excluded: true},
+		{path: "node_modules"},
+		{path: "x.log", excluded: true},
+		{path: "a/keep.log"},
+		{path: "gen", isDir: true},
+		{path: "a/gen", isDir: true, excluded: true},
+		{path: "a/b/gen", isDir: true},
+		{path: "x.tmp"},
+		{path: "a/x.tmp", excluded: true},
+		{path: "a/b/x.tmp"},
+		{path: "a/b/y.tmp", excluded: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			if got := gi.match(strings.Split(test.path, "/"), test.isDir); got != test.excluded {
+				t.Errorf("%v != %v", got, test.excluded)
+			}
+		})
+	}
+
+	// scanning a subdirectory, paths are relative to it.
+	sub, err := newGitIgnores(zlog.NewNopLogger(), filepath.Join(root, "repo", "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subTests := []struct {
+		path     string
+		isDir    bool
+		excluded bool
+	}{
+		{path: "main.go"},
+		{path: "x.exclude", excluded: true},
+		{path: "x.log", excluded: true},
+		{path: "keep.log"},
+		{path: "gen", isDir: true, excluded: true},
+		{path: "b/gen", isDir: true},
+		{path: "x.tmp", excluded: true},
+		{path: "b/x.tmp"},
+	}
+
+	for _, test := range subTests {
+		t.Run("a/"+test.path, func(t *testing.T) {
+			if got := sub.match(strings.Split(test.path, "/"), test.isDir); got != test.excluded {
+				t.Errorf("%v != %v", got, test.excluded)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package scanner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cluttercode/clutter/pkg/gitrepo"
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+// NewGitScanner is like NewScanner, but takes the list of files to scan from
+// the git repository at root instead of walking the file system.
+//
+// If rev is empty, the files tracked in the working tree are scanned as they
+// are on disk. Otherwise the files in the tree of the commit rev are scanned,
+// read directly from the git object database. Paths ignored by cfg are
+// excluded in both cases.
+func NewGitScanner(z *zlog.Logger, cfg Config, rev string) (func(root string, f func(*RawElement) error) ([]*RawElement, error), error) {
+	if rev == "" {
+		cfg.Source = SourceGit
+		return NewScanner(z, cfg)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return func(root string, f func(*RawElement) error) ([]*RawElement, error) {
+		filter, err := NewFilter(z, cfg, root)
+		if err != nil {
+			return nil, err
+		}
+
+		repo, rel, err := OpenGitRepo(root)
+		if err != nil {
+			return nil, err
+		}
+
+		h, err := repo.ResolveRev(rev)
+		if err != nil {
+			return nil, fmt.Errorf("git rev: %w", err)
+		}
+
+		z.Infow("scanning git tree", "rev", rev, "commit", h)
+
+		var (
+			paths []string
+			blobs = map[string]gitrepo.Hash{}
+		)
+
+		if err := repo.WalkTree(h, func(path string, mode uint32, blob gitrepo.Hash) error {
+			if path, ok := rel(path); ok && includeGitPath(filter, path, mode) {
+				path = filepath.Join(root, filepath.FromSlash(path))
+				paths = append(paths, path)
+				blobs[path] = blob
+			}
+
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("git tree: %w", err)
+		}
+
+		scanBlob := func(z *zlog.Logger, path string, f func(*RawElement) error) error {
+			bs, err := repo.ReadBlob(blobs[path])
+			if err != nil {
+				return err
+			}
+
+			return ScanReader(z, cfg, path, bytes.NewReader(bs), f)
+		}
+
+		return scanPaths(z, cfg, paths, scanBlob, nil, f)
+	}, nil
+}
+
+// listGitIndex lists the files tracked by the git repository at root that
+// exist in the working tree.
+func listGitIndex(root string, filter func(string, os.FileInfo) (bool, error)) ([]string, error) {
+	repo, rel, err := OpenGitRepo(root)
+	if err != nil {
+		return nil, err
+	}
+
+	ents, err := repo.ReadIndex()
+	if err != nil {
+		return nil, fmt.Errorf("git index: %w", err)
+	}
+
+	var paths []string
+
+	for _, ent := range ents {
+		p, ok := rel(ent.Path)
+		if !ok || !includeGitPath(filter, p, ent.Mode) {
+			continue
+		}
+
+		path := filepath.Join(root, filepath.FromSlash(p))
+
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			// deleted, but not staged.
+			continue
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// OpenGitRepo opens the git repository that root is in, which is root or
+// one of its parents. The returned function maps a slash separated path in
+// the repository to one relative to root, and reports false for paths that
+// are not under root.
+func OpenGitRepo(root string) (*gitrepo.Repo, func(string) (string, bool), error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	top := findRepoRoot(abs)
+
+	repo, err := gitrepo.Open(top)
+	if err != nil {
+		return nil, nil, fmt.Errorf("git: %w", err)
+	}
+
+	prefix, err := filepath.Rel(top, abs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if prefix == "." {
+		return repo, func(path string) (string, bool) { return path, true }, nil
+	}
+
+	prefix = filepath.ToSlash(prefix) + "/"
+
+	return repo, func(path string) (string, bool) {
+		if !strings.HasPrefix(path, prefix) {
+			return "", false
+		}
+
+		return strings.TrimPrefix(path, prefix), true
+	}, nil
+}
+
+// NewGitPathFilter returns a function that reports whether a file tracked
+// by git under root, at a slash separated path relative to root and with the
+// given mode, is scanned according to cfg.
+func NewGitPathFilter(z *zlog.Logger, cfg Config, root string) (func(path string, mode uint32) bool, error) {
+	filter, err := NewFilter(z, cfg, root)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(path string, mode uint32) bool { return includeGitPath(filter, path, mode) }, nil
+}
+
+// includeGitPath applies filter to a slash separated path from git.
+func includeGitPath(filter func(string, os.FileInfo) (bool, error), path string, mode uint32) bool {
+	if mode == gitrepo.ModeSymlink || mode == gitrepo.ModeGitlink {
+		return false
+	}
+
+	// all parent dirs are checked as well, as there is no walk here that
+	// would skip excluded dirs.
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		if ok, err := filter(filepath.Join(parts[:i]...), dirInfo{}); !ok && err == filepath.SkipDir {
+			return false
+		}
+	}
+
+	ok, _ := filter(filepath.FromSlash(path), nil)
+
+	return ok
+}
+
+// dirInfo is used to ask the filter about directories that are not on disk.
+type dirInfo struct{ os.FileInfo }
+
+func (dirInfo) IsDir() bool       { return true }
+func (dirInfo) Mode() os.FileMode { return os.ModeDir }
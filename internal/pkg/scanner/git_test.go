@@ -0,0 +1,101 @@
+// [# %stop! #]
+
+package scanner
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func git(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(
+		[]string{"PATH=" + os.Getenv("PATH")},
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+dir,
+	)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+}
+
+func TestGitScannerSubdir(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "clutter-git-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	sub := filepath.Join(dir, "sub")
+
+	if err := os.MkdirAll(filepath.Join(sub, "d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(path, text string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, path), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git(t, dir, "init", "-q")
+
+	write("top", "[# top #]\n")
+	write("sub/a", "[# a #]\n")
+	write("sub/d/b", "[# b #]\n")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-qm", "first")
+
+	write("sub/a", "[# a2 #]\n")
+
+	tests := []struct {
+		rev string
+		exp []string
+	}{
+		{rev: "", exp: []string{"a:1.1-8 a2", "d/b:1.1-7 b"}},
+		{rev: "HEAD", exp: []string{"a:1.1-7 a", "d/b:1.1-7 b"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.rev, func(t *testing.T) {
+			scan, err := NewGitScanner(zlog.NewNopLogger(), Config{Bracket: BracketConfig{Left: "[#", Right: "#]"}}, test.rev)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			elems, err := scan(sub, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, len(elems))
+			for i, elem := range elems {
+				rel, err := filepath.Rel(sub, elem.Loc.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				elem.Loc.Path = filepath.ToSlash(rel)
+				got[i] = elem.Loc.String() + " " + elem.Text
+			}
+
+			if !reflect.DeepEqual(got, test.exp) {
+				t.Errorf("%v != %v", got, test.exp)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package scanner
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Language describes how comments and string literals are delimited in a
+// source language. It is used in ModeComments to determine which parts of a
+// file are comments.
+type Language struct {
+	Name string
+
+	LineComments  []string    // e.g. "//".
+	BlockComments [][2]string // e.g. {"/*", "*/"}.
+
+	Strings    []string // delimiters of strings that support backslash escaping.
+	RawStrings []string // delimiters of strings that do not.
+
+	// MultiLineStrings are the delimiters in Strings of strings that may span
+	// lines. Other strings end at the end of their line, even if unterminated.
+	// Raw strings may always span lines.
+	MultiLineStrings []string
+
+	// CharLiterals is set for languages in which ' delimits character
+	// literals, but is not a string delimiter since it is also used otherwise.
+	CharLiterals bool
+}
+
+var (
+	langC = &Language{
+		Name:          "c",
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Strings:       []string{`"`, `'`},
+	}
+
+	langGo = &Language{
+		Name:          "go",
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Strings:       []string{`"`, `'`},
+		RawStrings:    []string{"`"},
+	}
+
+	langJS = &Language{
+		Name:             "js",
+		LineComments:     []string{"//"},
+		BlockComments:    [][2]string{{"/*", "*/"}},
+		Strings:          []string{`"`, `'`, "`"},
+		MultiLineStrings: []string{"`"},
+	}
+
+	langRust = &Language{
+		Name:          "rust",
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Strings:       []string{`"`}, // ' is also used for lifetimes.
+		CharLiterals:  true,
+	}
+
+	langCSS = &Language{
+		Name:          "css",
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Strings:       []string{`"`, `'`},
+	}
+
+	langHash = &Language{
+		Name:         "hash",
+		LineComments: []string{"#"},
+		Strings:      []string{`"`, `'`},
+	}
+
+	langPython = &Language{
+		Name:             "python",
+		LineComments:     []string{"#"},
+		Strings:          []string{`"""`, `'''`, `"`, `'`},
+		MultiLineStrings: []string{`"""`, `'''`},
+	}
+
+	langHaskell = &Language{
+		Name:          "haskell",
+		LineComments:  []string{"--"},
+		BlockComments: [][2]string{{"{-", "-}"}},
+		Strings:       []string{`"`}, // ' is also used in identifiers.
+		CharLiterals:  true,
+	}
+
+	langLua = &Language{
+		Name:          "lua",
+		LineComments:  []string{"--"},
+		BlockComments: [][2]string{{"--[[", "]]"}},
+		Strings:       []string{`"`, `'`},
+	}
+
+	langSQL = &Language{
+		Name:          "sql",
+		LineComments:  []string{"--"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Strings:       []string{`'`},
+	}
+
+	langXML = &Language{
+		Name:          "xml",
+		BlockComments: [][2]string{{"<!--", "-->"}},
+	}
+
+	langVim = &Language{
+		Name:         "vim",
+		LineComments: []string{`"`},
+	}
+)
+
+var langsByExt = map[string]*Language{
+	".c":     langC,
+	".h":     langC,
+	".cc":    langC,
+	".cpp":   langC,
+	".cxx":   langC,
+	".hh":    langC,
+	".hpp":   langC,
+	".cs":    langC,
+	".java":  langC,
+	".kt":    langC,
+	".scala": langC,
+	".swift": langC,
+	".dart":  langC,
+	".proto": langC,
+	".php":   langC,
+	".go":    langGo,
+	".js":    langJS,
+	".jsx":   langJS,
+	".mjs":   langJS,
+	".ts":    langJS,
+	".tsx":   langJS,
+	".rs":    langRust,
+	".css":   langCSS,
+	".scss":  langCSS,
+	".sh":    langHash,
+	".bash":  langHash,
+	".zsh":   langHash,
+	".rb":    langHash,
+	".pl":    langHash,
+	".r":     langHash,
+	".yaml":  langHash,
+	".yml":   langHash,
+	".toml":  langHash,
+	".py":    langPython,
+	".hs":    langHaskell,
+	".lua":   langLua,
+	".sql":   langSQL,
+	".html":  langXML,
+	".htm":   langXML,
+	".xml":   langXML,
+	".vim":   langVim,
+}
+
+var langsByName = map[string]*Language{
+	"Makefile":   langHash,
+	"Dockerfile": langHash,
+}
+
+// LanguageForPath returns the language of the file at path, by its extension
+// or name. nil is returned for unknown languages, and for prose formats such
+// as markdown, where the whole file is considered to be a comment.
+func LanguageForPath(path string) *Language {
+	base := filepath.Base(path)
+
+	if l := langsByName[base]; l != nil {
+		return l
+	}
+
+	return langsByExt[strings.ToLower(filepath.Ext(base))]
+}
+
+func hasAnyPrefix(bs []byte, ps []string) string {
+	for _, p := range ps {
+		if bytes.HasPrefix(bs, []byte(p)) {
+			return p
+		}
+	}
+
+	return ""
+}
+
+// maskCode returns a copy of src in which everything that is not a comment
+// body is replaced by spaces. Newlines are preserved, so lines and columns in
+// the masked copy are the same as in src.
+func (l *Language) maskCode(src []byte) []byte {
+	// masking is done byte by byte (rather than rune by rune) to keep columns
+	// intact, since columns are byte offsets.
+	out := make([]byte, len(src))
+	for i, b := range src {
+		if out[i] = ' '; b == '\n' {
+			out[i] = b
+		}
+	}
+
+	keep := func(from, to int) {
+		if to > len(src) {
+			to = len(src)
+		}
+
+		copy(out[from:to], src[from:to])
+	}
+
+	skipString := func(i int, delim string, escape, multiLine bool) int {
+		for i < len(src) {
+			if escape && src[i] == '\\' {
+				i += 2
+				continue
+			}
+
+			if bytes.HasPrefix(src[i:], []byte(delim)) {
+				return i + len(delim)
+			}
+
+			if !multiLine && src[i] == '\n' {
+				return i
+			}
+
+			i++
+		}
+
+		return i
+	}
+
+	// skipChar returns the end of the character literal at i, or i if there
+	// is none, as the quote is then used otherwise: '"', '\'' and '\u{7f}'
+	// are literals, while 'a in a Rust lifetime or a' in a Haskell identifier
+	// are not.
+	skipChar := func(i int) int {
+		rest := src[i+1:]
+
+		if bytes.HasPrefix(rest, []byte{'\\'}) {
+			if len(rest) < 2 {
+				return i
+			}
+
+			end := bytes.IndexAny(rest[2:], "'\n")
+			if end < 0 || rest[2+end] != '\'' {
+				return i
+			}
+
+			return i + 1 + 2 + end + 1
+		}
+
+		r, n := utf8.DecodeRune(rest)
+		if r == utf8.RuneError || r == '\n' || r == '\'' || !bytes.HasPrefix(rest[n:], []byte{'\''}) {
+			return i
+		}
+
+		return i + 1 + n + 1
+	}
+
+S:
+	for i := 0; i < len(src); {
+		rest := src[i:]
+
+		// blocks first, as their prefix might also be a line comment prefix (lua).
+		for _, b := range l.BlockComments {
+			if !bytes.HasPrefix(rest, []byte(b[0])) {
+				continue
+			}
+
+			start := i + len(b[0])
+
+			end := bytes.Index(src[start:], []byte(b[1]))
+			if end < 0 {
+				keep(start, len(src))
+				break S
+			}
+
+			keep(start, start+end)
+
+			i = start + end + len(b[1])
+
+			continue S
+		}
+
+		if p := hasAnyPrefix(rest, l.LineComments); p != "" {
+			start := i + len(p)
+
+			end := bytes.IndexByte(src[start:], '\n')
+			if end < 0 {
+				keep(start, len(src))
+				break S
+			}
+
+			keep(start, start+end)
+
+			i = start + end
+
+			continue S
+		}
+
+		if p := hasAnyPrefix(rest, l.Strings); p != "" {
+			i = skipString(i+len(p), p, true, hasAnyPrefix([]byte(p), l.MultiLineStrings) == p)
+			continue S
+		}
+
+		if p := hasAnyPrefix(rest, l.RawStrings); p != "" {
+			i = skipString(i+len(p), p, false, true)
+			continue S
+		}
+
+		if l.CharLiterals && rest[0] == '\'' {
+			if end := skipChar(i); end > i {
+				i = end
+				continue S
+			}
+		}
+
+		i++
+	}
+
+	return out
+}
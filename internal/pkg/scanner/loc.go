@@ -10,9 +10,19 @@ type Loc struct {
 	Path        string
 	Line        int
 	StartColumn int
+	EndLine     int // 0 if the tag ends on Line.
 	EndColumn   int
 }
 
+// LastLine returns the line the tag ends on.
+func (l Loc) LastLine() int {
+	if l.EndLine > l.Line {
+		return l.EndLine
+	}
+
+	return l.Line
+}
+
 func (l *Loc) Less(other Loc) bool {
 	if x, y := l.Path, other.Path; x != y {
 		return l.Path < other.Path
@@ -27,21 +37,43 @@ func (l *Loc) Less(other Loc) bool {
 }
 
 func (l Loc) Contains(other Loc) bool {
-	return l.Path == other.Path &&
-		l.Line == other.Line &&
-		other.StartColumn >= l.StartColumn &&
-		other.EndColumn <= l.EndColumn
+	if l.Path != other.Path {
+		return false
+	}
+
+	if l.LastLine() == l.Line {
+		return l.Line == other.Line &&
+			other.StartColumn >= l.StartColumn &&
+			other.EndColumn <= l.EndColumn
+	}
+
+	// multi-line: other must start after our start and end before our end.
+
+	if other.Line < l.Line || (other.Line == l.Line && other.StartColumn < l.StartColumn) {
+		return false
+	}
+
+	if last := other.LastLine(); last > l.EndLine || (last == l.EndLine && other.EndColumn > l.EndColumn) {
+		return false
+	}
+
+	return true
 }
 
 func (l Loc) String() string {
+	if l.LastLine() != l.Line {
+		return fmt.Sprintf("%s:%d.%d-%d.%d", l.Path, l.Line, l.StartColumn, l.EndLine, l.EndColumn)
+	}
+
 	return fmt.Sprintf("%s:%d.%d-%d", l.Path, l.Line, l.StartColumn, l.EndColumn)
 }
 
-var locRegexp = regexp.MustCompile(`^(.+):([0-9]+)\.([0-9]+)(-[0-9]+)?$`)
+// path:line.col, path:line.startcol-endcol or path:line.startcol-endline.endcol.
+var locRegexp = regexp.MustCompile(`^(.+):([0-9]+)\.([0-9]+)(-(([0-9]+)\.)?([0-9]+))?$`)
 
 func ParseLocString(text string) (*Loc, error) {
 	ms := locRegexp.FindAllStringSubmatch(text, -1)
-	if len(ms) != 1 || len(ms[0]) < 5 {
+	if len(ms) != 1 || len(ms[0]) < 8 {
 		return nil, fmt.Errorf("invalid")
 	}
 
@@ -58,12 +90,26 @@ func ParseLocString(text string) (*Loc, error) {
 		return nil, fmt.Errorf("invalid start column")
 	}
 
-	if rest := ms[0][4]; rest != "" {
-		if loc.EndColumn, err = strconv.Atoi(rest[1:]); err != nil {
+	if ms[0][4] != "" {
+		if endLine := ms[0][6]; endLine != "" {
+			if loc.EndLine, err = strconv.Atoi(endLine); err != nil {
+				return nil, fmt.Errorf("invalid end line")
+			}
+
+			if loc.EndLine < loc.Line {
+				return nil, fmt.Errorf("invalid end line")
+			}
+
+			if loc.EndLine == loc.Line {
+				loc.EndLine = 0
+			}
+		}
+
+		if loc.EndColumn, err = strconv.Atoi(ms[0][7]); err != nil {
 			return nil, fmt.Errorf("invalid end column")
 		}
 
-		if loc.EndColumn <= loc.StartColumn {
+		if loc.EndLine == 0 && loc.EndColumn <= loc.StartColumn {
 			return nil, fmt.Errorf("invalid end column")
 		}
 	} else {
@@ -30,7 +30,7 @@ func isText(s []byte) bool {
 
 func ScanFile(
 	z *zlog.Logger,
-	cfg BracketConfig,
+	cfg Config,
 	path string,
 	f func(*RawElement) error,
 ) error {
@@ -47,10 +47,22 @@ func ScanFile(
 		r = fp
 	}
 
+	return ScanReader(z, cfg, path, r, f)
+}
+
+// ScanReader scans r as if it is the content of the file at path. path
+// determines the file language when scanning only comments.
+func ScanReader(
+	z *zlog.Logger,
+	cfg Config,
+	path string,
+	r io.Reader,
+	f func(*RawElement) error,
+) error {
 	buf := make([]byte, 128)
 
-	n, err := r.Read(buf)
-	if err != nil && err != io.EOF {
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		return fmt.Errorf("read: %w", err)
 	}
 
@@ -61,13 +73,25 @@ func ScanFile(
 
 	r = io.MultiReader(bytes.NewReader(buf[:n]), r)
 
-	return ScanRawReader(
-		z,
-		cfg,
-		r,
-		func(e *RawElement) error {
-			e.Loc.Path = path // [# .fill-path #]
-			return f(e)
-		},
-	)
+	fill := func(e *RawElement) error {
+		e.Loc.Path = path // [# .fill-path #]
+		return f(e)
+	}
+
+	switch cfg.Mode {
+	case "", ModeRaw:
+		return ScanRawReader(z, cfg.Bracket, r, fill)
+
+	case ModeComments:
+		if lang := LanguageForPath(path); lang != nil {
+			return ScanSitterReader(z, cfg.Bracket, lang, r, fill)
+		}
+
+		z.Debug("unknown language, scanning entire file as a comment")
+
+		return scanLines(z, cfg.Bracket, r, true, false, fill)
+
+	default:
+		return fmt.Errorf("invalid mode: %q", cfg.Mode)
+	}
 }
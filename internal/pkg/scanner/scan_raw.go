@@ -9,11 +9,41 @@ import (
 	"github.com/cluttercode/clutter/pkg/zlog"
 )
 
+// Leaders that are stripped from the beginning of continuation lines of
+// multi-line tags. The leader of block comment lines, "*", only continues
+// tags that began inside a block comment, so that markdown and plain text
+// bullets are not joined into tags.
+var (
+	commentLeaders      = []string{"//", "--", "#"}
+	blockCommentLeaders = []string{"//", "--", "#", "*"}
+)
+
+// A tag that spans more lines than this is considered to be unterminated.
+const maxTagLines = 16
+
+// ScanRawReader scans all of r for tags. Multi-line tags are recognized only
+// if all their continuation lines begin with a comment leader or the right
+// bracket, to avoid matching brackets that appear in code.
 func ScanRawReader(
 	z *zlog.Logger,
 	cfg BracketConfig,
 	r io.Reader,
 	f func(*RawElement) error, // will not include path. path is filled in [# ./fill-path #].
+) error {
+	return scanLines(z, cfg, r, false, false, f)
+}
+
+// scanLines scans r for tags. If allComment is true, r is assumed to contain
+// only comments, and any line that is not a bullet may continue a multi-line
+// tag. If allBlock is true, r is assumed to be masked code (see maskCode),
+// where the block comment delimiters are gone, and all tags are treated as if
+// they began inside a block comment.
+func scanLines(
+	z *zlog.Logger,
+	cfg BracketConfig,
+	r io.Reader,
+	allComment, allBlock bool,
+	f func(*RawElement) error,
 ) error {
 	re, err := cfg.Regexp()
 	if err != nil {
@@ -24,68 +54,240 @@ func ScanRawReader(
 
 	stopped := false
 
+	// [# nolint-pragmas #]: rules suppressed by %nolint apply to the rest of
+	// its line, or if there are no more tags on that line, to the next tag.
+	// Rules suppressed by %nolint-file apply to the whole file, so elements
+	// are only reported once it is completely scanned.
+	var (
+		noLint       []string
+		noLintLine   int
+		noLintUsed   bool
+		noLintFile   []string
+		noLintFileOn = map[string]bool{}
+		elems        []*RawElement
+	)
+
+	// emit returns true if scanning should stop.
+	emit := func(text string, loc Loc) (bool, error) {
+		text = strings.TrimPrefix(text, cfg.Left)
+		text = strings.TrimSuffix(text, cfg.Right)
+		text = strings.TrimSpace(text)
+
+		if strings.HasPrefix(text, "%") {
+			if fields := strings.Fields(text[1:]); len(fields) > 0 {
+				switch fields[0] {
+				case "nolint":
+					if len(fields) == 1 {
+						return false, fmt.Errorf("%d.%d: %%nolint: no rules specified", loc.Line, loc.StartColumn)
+					}
+
+					noLint, noLintLine, noLintUsed = fields[1:], loc.Line, false
+
+					return false, nil
+
+				case "nolint-file":
+					if len(fields) == 1 {
+						return false, fmt.Errorf("%d.%d: %%nolint-file: no rules specified", loc.Line, loc.StartColumn)
+					}
+
+					for _, r := range fields[1:] {
+						if !noLintFileOn[r] {
+							noLintFileOn[r] = true
+							noLintFile = append(noLintFile, r)
+						}
+					}
+
+					return false, nil
+				}
+			}
+
+			switch text[1:] {
+			case "stop!":
+				// hard stop will stop scanning the rest of the file.
+				return true, nil
+
+			case "stop":
+				if stopped {
+					return false, fmt.Errorf("already stopped")
+				}
+
+				stopped = true
+			case "cont":
+				if !stopped {
+					return false, fmt.Errorf("not stopped")
+				}
+
+				stopped = false
+			default:
+				return false, fmt.Errorf("unknown pragma: %s", text)
+			}
+
+			return false, nil
+		}
+
+		if stopped {
+			return false, nil
+		}
+
+		elem := RawElement{Text: text, Loc: loc}
+
+		if noLint != nil {
+			switch {
+			case loc.Line == noLintLine:
+				elem.NoLint, noLintUsed = noLint, true
+			case !noLintUsed:
+				elem.NoLint, noLint = noLint, nil
+			default:
+				noLint = nil
+			}
+		}
+
+		elems = append(elems, &elem)
+
+		return false, nil
+	}
+
+	// a tag that was opened but not yet closed, see [# multi-line-tags #].
+	var (
+		pending      []string
+		pendingLoc   Loc
+		pendingBlock bool // began inside a block comment.
+		inBlock      bool // inside a block comment at the end of the last line.
+	)
+
 S:
 	for i := 0; scanner.Scan(); i++ {
 		line := scanner.Text()
 
-		ms := re.FindAllStringIndex(line, -1)
+		lineBlock := inBlock
+		inBlock = inBlockComment(inBlock, line)
 
-		for _, m := range ms {
-			l, r := m[0], m[1]
+		offset := 0 // where to start looking for tags in line.
 
-			text := line[l:r]
-			text = strings.TrimPrefix(text, cfg.Left)
-			text = strings.TrimSuffix(text, cfg.Right)
-			text = strings.TrimSpace(text)
+		if pending != nil {
+			cont := strings.TrimSpace(line)
 
-			if strings.HasPrefix(text, "%") {
-				switch text[1:] {
-				case "stop!":
-					// hard stop will stop scanning the rest of the file.
-					break S
+			isCont := strings.HasPrefix(cont, cfg.Right) || (allComment && !strings.HasPrefix(cont, "*"))
 
-				case "stop":
-					if stopped {
-						return fmt.Errorf("already stopped")
-					}
+			leaders := commentLeaders
+			if pendingBlock {
+				leaders = blockCommentLeaders
+			}
 
-					stopped = true
-				case "cont":
-					if !stopped {
-						return fmt.Errorf("not stopped")
+			if !strings.HasPrefix(cont, cfg.Right) {
+				for _, l := range leaders {
+					if strings.HasPrefix(cont, l) {
+						cont = strings.TrimSpace(strings.TrimPrefix(cont, l))
+						isCont = true
+						break
 					}
+				}
+			}
+
+			left, right := strings.Index(line, cfg.Left), strings.Index(line, cfg.Right)
+
+			switch {
+			case !isCont || i+1-pendingLoc.Line >= maxTagLines:
+				z.Debugw("abandoning unterminated tag", "loc", pendingLoc)
+
+				pending = nil
+
+			case right >= 0 && (left < 0 || right < left):
+				cont = cont[:strings.Index(cont, cfg.Right)+len(cfg.Right)]
 
-					stopped = false
-				default:
-					return fmt.Errorf("unknown pragma: %s", text)
+				loc := pendingLoc
+				loc.EndLine, loc.EndColumn = i+1, right+len(cfg.Right)
+
+				text := strings.Join(append(pending, cont), " ")
+
+				pending = nil
+
+				if stop, err := emit(text, loc); err != nil {
+					return err
+				} else if stop {
+					break S
 				}
 
-				continue
-			}
+				offset = right + len(cfg.Right)
 
-			if stopped {
-				continue
+			case left >= 0:
+				z.Debugw("abandoning unterminated tag", "loc", pendingLoc)
+
+				pending = nil
+
+			default:
+				if cont != "" {
+					pending = append(pending, cont)
+				}
+
+				continue S
 			}
+		}
 
-			if err := f(&RawElement{
-				Text: text,
-				Loc: Loc{
-					Line:        i + 1,
-					StartColumn: l + 1,
-					EndColumn:   r,
-				},
+		rest := line[offset:]
+
+		for _, m := range re.FindAllStringIndex(rest, -1) {
+			l, r := offset+m[0], offset+m[1]
+
+			if stop, err := emit(line[l:r], Loc{
+				Line:        i + 1,
+				StartColumn: l + 1,
+				EndColumn:   r,
 			}); err != nil {
-				return fmt.Errorf("%d.%d: %w", i+1, l+1, err)
+				return err
+			} else if stop {
+				break S
 			}
+
+			rest = line[r:]
+		}
+
+		// [# multi-line-tags #]: a tag that is opened but not closed on the
+		// same line might be closed on one of the following lines.
+		if l := strings.LastIndex(rest, cfg.Left); l >= 0 && !strings.Contains(rest[l:], cfg.Right) {
+			l += len(line) - len(rest)
+
+			pending = []string{line[l:]}
+			pendingLoc = Loc{Line: i + 1, StartColumn: l + 1}
+			pendingBlock = allBlock || inBlockComment(lineBlock, line[:l])
 		}
 	}
 
+	if pending != nil {
+		z.Debugw("unterminated tag at end of file", "loc", pendingLoc)
+	}
+
 	if err := scanner.Err(); err == bufio.ErrTooLong {
 		z.Warn("file has tokens that are too long")
-		return nil
 	} else if err != nil {
 		return fmt.Errorf("scan: %w", err)
 	}
 
+	for _, elem := range elems {
+		elem.NoLintFile = noLintFile
+
+		if err := f(elem); err != nil {
+			return fmt.Errorf("%d.%d: %w", elem.Loc.Line, elem.Loc.StartColumn, err)
+		}
+	}
+
 	return nil
 }
+
+// inBlockComment reports whether the end of s is inside a block comment,
+// given whether its beginning is.
+func inBlockComment(in bool, s string) bool {
+	for {
+		delim := "/*"
+		if in {
+			delim = "*/"
+		}
+
+		i := strings.Index(s, delim)
+		if i < 0 {
+			return in
+		}
+
+		s, in = s[i+len(delim):], !in
+	}
+}
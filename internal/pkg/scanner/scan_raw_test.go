@@ -0,0 +1,219 @@
+// [# %stop! #]
+
+package scanner
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestScanRawReader(t *testing.T) {
+	tests := []struct {
+		text string
+		exp  []string // "loc text"
+		err  bool
+	}{
+		{
+			text: "[# a #] [# b #]",
+			exp:  []string{"f:1.1-7 a", "f:1.9-15 b"},
+		},
+		{
+			text: "// [# api-contract owner=payments\n// see=docs/api #]",
+			exp:  []string{"f:1.4-2.18 api-contract owner=payments see=docs/api"},
+		},
+		{
+			text: "/* [# a\n * b=1\n *\n * c #] [# d #] */",
+			exp:  []string{"f:1.4-4.7 a b=1 c", "f:4.9-15 d"},
+		},
+		{
+			text: "* [# a\n* b #]\n* [# c #]",
+			exp:  []string{"f:3.3-9 c"},
+		},
+		{
+			text: "x /* y */ [# a\n * b #]",
+		},
+		{
+			text: "/**\n * [# a\n * b #]\n */ [# c\n * d #]",
+			exp:  []string{"f:2.4-3.7 a b"},
+		},
+		{
+			text: "* [# a\n* b #]\n* [# c #]",
+			exp:  []string{"f:3.3-9 c"},
+		},
+		{
+			text: "# [# a\n#]",
+			exp:  []string{"f:1.3-2.2 a"},
+		},
+		{
+			text: "-- [# a #] [# b\n-- c=\"x y\" #]",
+			exp:  []string{"f:1.4-10 a", "f:1.12-2.13 b c=\"x y\""},
+		},
+		{
+			text: "x := \"[#\",\ny := \"#]\"",
+		},
+		{
+			text: "[# unterminated\n[# a #]",
+			exp:  []string{"f:2.1-7 a"},
+		},
+		{
+			text: "[# unterminated" + strings.Repeat("\n", maxTagLines) + "#] [# a #]",
+			exp:  []string{"f:17.4-10 a"},
+		},
+		{
+			text: "[# %stop\n#] [# a #] [# %cont #] [# b #]",
+			exp:  []string{"f:2.24-30 b"},
+		},
+		{
+			text: "[# %nosuchpragma\n#]",
+			err:  true,
+		},
+	}
+
+	cfg := BracketConfig{Left: "[#", Right: "#]"}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			var got []string
+
+			err := ScanRawReader(
+				zlog.NewNopLogger(),
+				cfg,
+				strings.NewReader(test.text),
+				func(e *RawElement) error {
+					e.Loc.Path = "f"
+
+					got = append(got, e.Loc.String()+" "+e.Text)
+
+					// make sure the loc round trips.
+					loc, err := ParseLocString(e.Loc.String())
+					if err != nil {
+						t.Errorf("parse loc: %v", err)
+					} else if *loc != e.Loc {
+						t.Errorf("loc: %v != %v", *loc, e.Loc)
+					}
+
+					return nil
+				},
+			)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("error expected, but got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			if !reflect.DeepEqual(test.exp, got) {
+				t.Errorf("%v != %v", test.exp, got)
+			}
+		})
+	}
+}
+
+func TestNoLintPragmas(t *testing.T) {
+	tests := []struct {
+		text string
+		exp  []string // "text nolint|nolint-file"
+		err  bool
+	}{
+		{
+			text: "[# a #] [# %nolint r #] [# b #] [# c #]\n[# d #]",
+			exp:  []string{"a |", "b r|", "c r|", "d |"},
+		},
+		{
+			text: "[# %nolint r s #]\n\n[# a #] [# b #]",
+			exp:  []string{"a r,s|", "b |"},
+		},
+		{
+			text: "[# a #]\n[# %nolint-file r #] [# b #]\n[# %nolint-file s r #]\n[# %nolint t #] [# c #]",
+			exp:  []string{"a |r,s", "b |r,s", "c t|r,s"},
+		},
+		{
+			text: "[# %nolint #]",
+			err:  true,
+		},
+		{
+			text: "[# %nolint-file #]",
+			err:  true,
+		},
+	}
+
+	cfg := BracketConfig{Left: "[#", Right: "#]"}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			var got []string
+
+			err := ScanRawReader(
+				zlog.NewNopLogger(),
+				cfg,
+				strings.NewReader(test.text),
+				func(e *RawElement) error {
+					got = append(got, e.Text+" "+strings.Join(e.NoLint, ",")+"|"+strings.Join(e.NoLintFile, ","))
+					return nil
+				},
+			)
+
+			if test.err {
+				if err == nil {
+					t.Errorf("error expected, but got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, test.exp) {
+				t.Errorf("%q != %q", got, test.exp)
+			}
+		})
+	}
+}
+
+func TestScanLinesBullets(t *testing.T) {
+	text := "* [# a\n  b #]\n* [# c\n* d #]\n* [# e #]"
+
+	tests := []struct {
+		allBlock bool
+		exp      []string
+	}{
+		{exp: []string{"1.3-2.6 a b", "5.3-9 e"}},
+		{allBlock: true, exp: []string{"1.3-2.6 a b", "3.3-4.6 c d", "5.3-9 e"}},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.allBlock), func(t *testing.T) {
+			var got []string
+
+			if err := scanLines(
+				zlog.NewNopLogger(),
+				BracketConfig{Left: "[#", Right: "#]"},
+				strings.NewReader(text),
+				true,
+				test.allBlock,
+				func(e *RawElement) error {
+					got = append(got, strings.TrimPrefix(e.Loc.String(), ":")+" "+e.Text)
+					return nil
+				},
+			); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(test.exp, got) {
+				t.Errorf("%v != %v", test.exp, got)
+			}
+		})
+	}
+}
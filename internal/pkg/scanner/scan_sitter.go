@@ -1,22 +1,34 @@
 package scanner
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 
 	"github.com/cluttercode/clutter/pkg/zlog"
 )
 
+// ScanSitterReader scans only the comments in r, as delimited by lang. Tags
+// appearing anywhere else, such as in string literals, are ignored. Locations
+// are reported exactly as ScanRawReader would report them.
 func ScanSitterReader(
 	z *zlog.Logger,
 	cfg BracketConfig,
+	lang *Language,
 	r io.Reader,
 	f func(*RawElement) error, // will not include path. path is filled in [# ./fill-path #].
 ) error {
-	_, err := cfg.Regexp()
-	if err != nil {
+	if _, err := cfg.Regexp(); err != nil {
 		return fmt.Errorf("invalid bracket: %w", err)
 	}
 
-	return nil
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+
+	z.Debugw("scanning comments", "lang", lang.Name)
+
+	return scanLines(z, cfg, bytes.NewReader(lang.maskCode(src)), true, true, f)
 }
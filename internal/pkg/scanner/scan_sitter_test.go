@@ -0,0 +1,114 @@
+// [# %stop! #]
+
+package scanner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestScanSitterReader(t *testing.T) {
+	tests := []struct {
+		path string
+		text string
+		exp  []string // "line.col-endcol text"
+	}{
+		{
+			path: "a.go",
+			text: "x := \"[# not-a-tag #]\" // [# tag #]",
+			exp:  []string{"1.27-35 tag"},
+		},
+		{
+			path: "a.go",
+			text: "x := `\n[# not-a-tag #]\n` /* [# tag1 #]\n[# tag2 #] */",
+			exp:  []string{"3.6-15 tag1", "4.1-10 tag2"},
+		},
+		{
+			path: "a.go",
+			text: "x := \"\\\" [# not-a-tag #]\"",
+		},
+		{
+			path: "a.py",
+			text: "'''\n[# not-a-tag #]\n''' # [# tag #]",
+			exp:  []string{"3.7-15 tag"},
+		},
+		{
+			path: "a.hs",
+			text: "cat = putStrLn(\"[# x #]\") -- [# cat lang=haskell #]",
+			exp:  []string{"1.30-51 cat lang=haskell"},
+		},
+		{
+			path: "a.rs",
+			text: "let q = '\"'; // [# tag #]\nfn f<'a>(s: &'a str) -> char { '\\'' } // [# tag2 #]",
+			exp:  []string{"1.17-25 tag", "2.42-51 tag2"},
+		},
+		{
+			path: "a.hs",
+			text: "q = '\"' -- [# tag #]\nx' = x -- [# tag2 #]",
+			exp:  []string{"1.12-20 tag", "2.11-20 tag2"},
+		},
+		{
+			path: "a.rs",
+			text: "// [# tag #]\nlet c = '\\",
+			exp:  []string{"1.4-12 tag"},
+		},
+		{
+			path: "a.c",
+			text: "/**\n * [# a\n * b #]\n */",
+			exp:  []string{"2.4-3.7 a b"},
+		},
+		{
+			path: "a.c",
+			text: "char *s = \"unterminated;\n// [# tag #]",
+			exp:  []string{"2.4-12 tag"},
+		},
+		{
+			path: "a.go",
+			text: "// [# %stop #]\n// [# x #]\n// [# %cont #]\n// [# y #]",
+			exp:  []string{"4.4-10 y"},
+		},
+		{
+			path: "Makefile",
+			text: "X=\"[# x #]\" # [# y #]",
+			exp:  []string{"1.15-21 y"},
+		},
+		{
+			path: "a.go",
+			text: "// unterminated [# x #]",
+			exp:  []string{"1.17-23 x"},
+		},
+	}
+
+	cfg := BracketConfig{Left: "[#", Right: "#]"}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			var got []string
+
+			lang := LanguageForPath(test.path)
+			if lang == nil {
+				t.Fatalf("no language for %q", test.path)
+			}
+
+			if err := ScanSitterReader(
+				zlog.NewNopLogger(),
+				cfg,
+				lang,
+				strings.NewReader(test.text),
+				func(e *RawElement) error {
+					got = append(got, strings.TrimPrefix(e.Loc.String(), ":")+" "+e.Text)
+					return nil
+				},
+			); err != nil {
+				t.Fatalf("got error: %v", err)
+			}
+
+			if !reflect.DeepEqual(test.exp, got) {
+				t.Errorf("%v != %v", test.exp, got)
+			}
+		})
+	}
+}
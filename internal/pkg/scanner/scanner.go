@@ -1,54 +1,197 @@
 package scanner
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
 
 	"github.com/cluttercode/clutter/pkg/zlog"
 )
 
+// NewScanner returns a function that scans all files under root, as listed
+// by NewLister. Files are scanned concurrently by cfg.Workers workers, but
+// elements are always returned (and passed to f) in the order in which the
+// files were listed.
+//
+// Per file errors do not stop the scan. They are returned together as
+// FileErrors, along with the elements from all other files.
 func NewScanner(z *zlog.Logger, cfg Config) (func(root string, f func(*RawElement) error) ([]*RawElement, error), error) {
-	filter, err := NewFilter(z, cfg)
+	list, err := NewLister(z, cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	return func(root string, f func(*RawElement) error) ([]*RawElement, error) {
-		if f == nil {
-			f = func(*RawElement) error { return nil }
+		paths, err := list(root)
+
+		var errs FileErrors
+		if err != nil && !errors.As(err, &errs) {
+			return nil, err
 		}
 
-		var elems []*RawElement
+		return scanPaths(z, cfg, paths, nil, errs, f)
+	}, nil
+}
+
+// NewLister returns a function that lists all files under root that should be
+// scanned. Files are found by walking the file system, or taken from the git
+// index, according to cfg.Source.
+//
+// Errors encountered while walking do not stop the walk. They are returned as
+// FileErrors along with all paths found.
+func NewLister(z *zlog.Logger, cfg Config) (func(root string) ([]string, error), error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
-		if err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
-			z := z.With("path", path)
+	return func(root string) ([]string, error) {
+		filter, err := NewFilter(z, cfg, root)
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.Source == SourceGit {
+			return listGitIndex(root, filter)
+		}
 
+		var (
+			paths []string
+			errs  FileErrors
+		)
+
+		if err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
 			if err != nil {
-				return fmt.Errorf("path %q: %w", path, err)
+				errs = append(errs, &FileError{Path: path, Err: err})
+				return nil
 			}
 
-			if include, err := filter(path, fi); !include {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
 				return err
 			}
 
-			if err := ScanFile(z, cfg.Bracket, path, func(elem *RawElement) error {
-				if err := f(elem); err != nil {
-					return err
-				}
-
-				elems = append(elems, elem)
-
-				return nil
-			}); err != nil {
-				return fmt.Errorf("file %s: tool: %w", path, err)
+			if include, err := filter(rel, fi); !include {
+				return err
 			}
 
+			paths = append(paths, path)
+
 			return nil
 		}); err != nil {
 			return nil, err
 		}
 
-		return elems, nil
+		if len(errs) != 0 {
+			return paths, errs
+		}
+
+		return paths, nil
 	}, nil
 }
+
+// ScanPaths is like the function returned by NewScanner, but scans only the
+// given paths. If scanFile is nil, ScanFile is used.
+func ScanPaths(
+	z *zlog.Logger,
+	cfg Config,
+	paths []string,
+	scanFile func(z *zlog.Logger, path string, f func(*RawElement) error) error,
+	f func(*RawElement) error,
+) ([]*RawElement, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return scanPaths(z, cfg, paths, scanFile, nil, f)
+}
+
+func numWorkers(cfg Config) int {
+	if cfg.Workers == 0 {
+		return runtime.NumCPU()
+	}
+
+	return cfg.Workers
+}
+
+// scanPaths scans all paths using scanFile on a pool of workers.
+func scanPaths(
+	z *zlog.Logger,
+	cfg Config,
+	paths []string,
+	scanFile func(*zlog.Logger, string, func(*RawElement) error) error,
+	errs FileErrors,
+	f func(*RawElement) error,
+) ([]*RawElement, error) {
+	if f == nil {
+		f = func(*RawElement) error { return nil }
+	}
+
+	if scanFile == nil {
+		scanFile = func(z *zlog.Logger, path string, f func(*RawElement) error) error {
+			return ScanFile(z, cfg, path, f)
+		}
+	}
+
+	type result struct {
+		elems []*RawElement
+		err   error
+	}
+
+	results := make([]result, len(paths))
+
+	var (
+		wg sync.WaitGroup
+		is = make(chan int)
+	)
+
+	for w := 0; w < numWorkers(cfg); w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range is {
+				path := paths[i]
+
+				results[i].err = scanFile(z.With("path", path), path, func(elem *RawElement) error {
+					results[i].elems = append(results[i].elems, elem)
+					return nil
+				})
+			}
+		}()
+	}
+
+	for i := range paths {
+		is <- i
+	}
+
+	close(is)
+
+	wg.Wait()
+
+	var elems []*RawElement
+
+	for i, r := range results {
+		if r.err != nil {
+			errs = append(errs, &FileError{Path: paths[i], Err: r.err})
+			continue
+		}
+
+		for _, elem := range r.elems {
+			if err := f(elem); err != nil {
+				return nil, fmt.Errorf("file %s: %w", paths[i], err)
+			}
+		}
+
+		elems = append(elems, r.elems...)
+	}
+
+	if len(errs) != 0 {
+		return elems, errs
+	}
+
+	return elems, nil
+}
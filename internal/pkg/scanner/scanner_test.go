@@ -0,0 +1,83 @@
+// [# %stop! #]
+
+package scanner
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+func TestNewScanner(t *testing.T) {
+	root, err := ioutil.TempDir("", "clutter-scanner-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(root)
+
+	var exp []string
+
+	for i := 0; i < 50; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("d%d", i%7))
+
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("f%02d", i))
+
+		text := fmt.Sprintf("[# a%d #] [# b%d #]", i, i)
+		if i%10 == 3 {
+			text = "[# %nosuchpragma #]"
+		}
+
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, workers := range []int{1, 4, 16} {
+		t.Run(fmt.Sprint(workers), func(t *testing.T) {
+			scan, err := NewScanner(zlog.NewNopLogger(), Config{
+				Bracket: BracketConfig{Left: "[#", Right: "#]"},
+				Workers: workers,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			elems, err := scan(root, nil)
+
+			var errs FileErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("expected FileErrors, got %v", err)
+			}
+
+			if len(errs) != 5 {
+				t.Errorf("expected 5 errors, got %d: %v", len(errs), errs)
+			}
+
+			got := make([]string, len(elems))
+			for i, elem := range elems {
+				got[i] = elem.Loc.String() + " " + elem.Text
+			}
+
+			if exp == nil {
+				exp = got
+			} else if !reflect.DeepEqual(exp, got) {
+				t.Errorf("%v != %v", exp, got)
+			}
+
+			if len(got) != 90 {
+				t.Errorf("expected 90 elements, got %d", len(got))
+			}
+		})
+	}
+}
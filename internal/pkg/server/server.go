@@ -0,0 +1,270 @@
+// Package server serves an index over HTTP: a JSON API for search, resolve
+// and file listing, and a minimal browsing UI.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/output"
+	"github.com/cluttercode/clutter/internal/pkg/resolver"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+type Server struct {
+	z *zlog.Logger
+
+	mu      sync.RWMutex
+	idx     *index.Index
+	updated time.Time
+}
+
+func New(z *zlog.Logger, idx *index.Index) *Server {
+	s := &Server{z: z}
+	s.SetIndex(idx)
+
+	return s
+}
+
+// SetIndex replaces the served index. idx must not be modified after.
+func (s *Server) SetIndex(idx *index.Index) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.idx, s.updated = idx, time.Now()
+}
+
+func (s *Server) index() *index.Index {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.idx
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/", s.handleUI)
+	mux.HandleFunc("/api/search", s.handleSearch)
+	mux.HandleFunc("/api/resolve", s.handleResolve)
+	mux.HandleFunc("/api/files", s.handleFiles)
+	mux.HandleFunc("/api/file", s.handleFile)
+	mux.HandleFunc("/api/status", s.handleStatus)
+
+	return mux
+}
+
+// httpError is an error with an HTTP status.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+
+func badRequest(format string, args ...interface{}) error {
+	return &httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+// reply writes v as JSON, or err as {"error": "..."}.
+func (s *Server) reply(w http.ResponseWriter, r *http.Request, v interface{}, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	status := http.StatusOK
+
+	if err != nil {
+		status = http.StatusInternalServerError
+
+		if herr, ok := err.(*httpError); ok {
+			status = herr.status
+		}
+
+		v = map[string]string{"error": err.Error()}
+	}
+
+	s.z.Infow("request", "method", r.Method, "url", r.URL, "status", status)
+
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.z.Warnw("write response", "err", err)
+	}
+}
+
+func records(ents []*index.Entry) []*output.Record {
+	recs := make([]*output.Record, len(ents))
+	for i, ent := range ents {
+		recs[i] = output.NewRecord(ent, "")
+	}
+
+	return recs
+}
+
+// handleSearch searches the index as search does. The query parameters are
+// name, type (exact, glob, regexp or query, default exact), and patterns for
+// attributes by their names.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	recs, err := s.search(r.URL.Query())
+	s.reply(w, r, recs, err)
+}
+
+func (s *Server) search(q url.Values) ([]*output.Record, error) {
+	ent := index.Entry{Name: q.Get("name"), Attrs: index.Attrs{"search": "exact"}}
+
+	for k, vs := range q {
+		switch k {
+		case "name":
+		case "type":
+			ent.Attrs["search"] = vs[0]
+		default:
+			ent.Attrs[k] = vs[0]
+		}
+	}
+
+	m, err := ent.Matcher()
+	if err != nil {
+		return nil, badRequest("matcher: %w", err)
+	}
+
+	var ents []*index.Entry
+
+	_ = index.ForEach(s.index(), func(ent *index.Entry) error {
+		if m(ent) {
+			ents = append(ents, ent)
+		}
+
+		return nil
+	})
+
+	return records(ents), nil
+}
+
+// handleResolve resolves the tag at loc, given as path:line.col, as resolve
+// does. With dir=next or dir=prev, only the next or previous use is
+// returned, cyclic if cyclic is set.
+func (s *Server) handleResolve(w http.ResponseWriter, r *http.Request) {
+	recs, err := s.resolve(r.URL.Query())
+	s.reply(w, r, recs, err)
+}
+
+func (s *Server) resolve(q url.Values) ([]*output.Record, error) {
+	loc, err := scanner.ParseLocString(q.Get("loc"))
+	if err != nil {
+		return nil, badRequest("loc: %w", err)
+	}
+
+	idx := s.index()
+
+	var what *index.Entry
+
+	_ = index.ForEach(idx, func(ent *index.Entry) error {
+		if ent.Loc.Contains(*loc) {
+			what = ent
+			return index.ErrStop
+		}
+
+		return nil
+	})
+
+	if what == nil {
+		return nil, &httpError{status: http.StatusNotFound, err: fmt.Errorf("no tag at loc")}
+	}
+
+	z := s.z.Named("resolver").With("what", what)
+
+	cyclic := q.Get("cyclic") != ""
+
+	var ents []*index.Entry
+
+	switch dir := q.Get("dir"); dir {
+	case "":
+		ents, err = resolver.ResolveList(z, what, idx)
+	case "next":
+		ents, err = resolver.ResolveNext(z, what, idx, cyclic)
+	case "prev":
+		ents, err = resolver.ResolvePrev(z, what, idx, cyclic)
+	default:
+		return nil, badRequest("invalid dir %q", dir)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("resolver: %w", err)
+	}
+
+	return records(index.NewIndex(ents).Slice()), nil
+}
+
+type file struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"` // number of entries in the file.
+}
+
+// handleFiles lists the files that have tags.
+func (s *Server) handleFiles(w http.ResponseWriter, r *http.Request) {
+	counts := make(map[string]int)
+
+	_ = index.ForEach(s.index(), func(ent *index.Entry) error {
+		counts[ent.Loc.Path]++
+		return nil
+	})
+
+	files := make([]file, 0, len(counts))
+	for path, n := range counts {
+		files = append(files, file{Path: path, Count: n})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+
+	s.reply(w, r, files, nil)
+}
+
+// handleFile lists the tags in the file at path, in order.
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+
+	var ents []*index.Entry
+
+	_ = index.ForEach(s.index(), func(ent *index.Entry) error {
+		if ent.Loc.Path == path {
+			ents = append(ents, ent)
+		}
+
+		return nil
+	})
+
+	sort.SliceStable(ents, func(i, j int) bool { return ents[i].Loc.Less(ents[j].Loc) })
+
+	s.reply(w, r, records(ents), nil)
+}
+
+type status struct {
+	Entries int       `json:"entries"`
+	Updated time.Time `json:"updated"` // when the index was last replaced.
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	st := status{Entries: s.idx.Size(), Updated: s.updated}
+	s.mu.RUnlock()
+
+	s.reply(w, r, st, nil)
+}
+
+func (s *Server) handleUI(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	_, _ = w.Write([]byte(uiHTML))
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+var testIndex = index.NewIndex([]*index.Entry{
+	{Name: "cat", Attrs: index.Attrs{"lang": "go"}, Loc: scanner.Loc{Path: "a.go", Line: 1, StartColumn: 1, EndColumn: 10}},
+	{Name: "cat", Attrs: index.Attrs{"lang": "py"}, Loc: scanner.Loc{Path: "b.py", Line: 3, StartColumn: 1, EndColumn: 10}},
+	{Name: "dog", Loc: scanner.Loc{Path: "a.go", Line: 5, StartColumn: 1, EndColumn: 10}},
+})
+
+// get requests url from h, and returns the status and the locs of the
+// returned records, or the raw body if it is not a list of records.
+func get(h http.Handler, url string) (int, string) {
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+
+	var recs []map[string]interface{}
+
+	if err := json.Unmarshal(w.Body.Bytes(), &recs); err != nil || (len(recs) > 0 && recs[0]["loc"] == nil) {
+		return w.Code, strings.TrimSpace(w.Body.String())
+	}
+
+	locs := make([]string, len(recs))
+	for i, r := range recs {
+		locs[i] = r["loc"].(string)
+	}
+
+	return w.Code, strings.Join(locs, " ")
+}
+
+func TestServer(t *testing.T) {
+	h := New(zlog.NewNopLogger(), testIndex).Handler()
+
+	tests := []struct {
+		url    string
+		status int
+		exp    string
+	}{
+		{url: "/api/search?name=cat", status: 200, exp: "a.go:1.1-10 b.py:3.1-10"},
+		{url: "/api/search?name=cat&lang=py", status: 200, exp: "b.py:3.1-10"},
+		{url: "/api/search?name=*&type=glob", status: 200, exp: "a.go:1.1-10 b.py:3.1-10 a.go:5.1-10"},
+		{url: "/api/search?name=(&type=regexp", status: 400},
+		{url: "/api/resolve?loc=a.go:1.2", status: 200, exp: "a.go:1.1-10 b.py:3.1-10"},
+		{url: "/api/resolve?loc=a.go:1.2&dir=next", status: 200, exp: "b.py:3.1-10"},
+		{url: "/api/resolve?loc=b.py:3.2&dir=next", status: 200, exp: ""},
+		{url: "/api/resolve?loc=b.py:3.2&dir=next&cyclic=1", status: 200, exp: "a.go:1.1-10"},
+		{url: "/api/resolve?loc=a.go:1.2&dir=up", status: 400},
+		{url: "/api/resolve?loc=a.go:2.1", status: 404},
+		{url: "/api/resolve?loc=x", status: 400},
+		{url: "/api/file?path=a.go", status: 200, exp: "a.go:1.1-10 a.go:5.1-10"},
+		{url: "/api/files", status: 200, exp: `[{"path":"a.go","count":2},{"path":"b.py","count":1}]`},
+		{url: "/nothing", status: 404},
+	}
+
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			status, body := get(h, test.url)
+
+			if status != test.status {
+				t.Fatalf("status %d != %d: %s", status, test.status, body)
+			}
+
+			if status == 200 && body != test.exp {
+				t.Errorf("%q != %q", body, test.exp)
+			}
+		})
+	}
+}
+
+func TestSetIndex(t *testing.T) {
+	s := New(zlog.NewNopLogger(), testIndex)
+
+	s.SetIndex(index.NewIndex(nil))
+
+	if _, body := get(s.Handler(), "/api/status"); !strings.HasPrefix(body, `{"entries":0,`) {
+		t.Errorf("unexpected status %s", body)
+	}
+
+	if _, body := get(s.Handler(), "/api/search?name=cat"); body != "" {
+		t.Errorf("unexpected search %q", body)
+	}
+}
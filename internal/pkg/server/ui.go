@@ -0,0 +1,120 @@
+package server
+
+// uiHTML is a single page that browses the index using the API.
+const uiHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>clutter</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+a { color: #0645ad; cursor: pointer; }
+input, select, button { font-size: 1em; }
+#main { display: flex; gap: 2em; }
+#files { min-width: 20em; }
+li { margin: 0.2em 0; }
+.loc, .attrs { color: #555; font-size: 0.9em; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<form id="search">
+<select id="type">
+<option value="exact">exact</option>
+<option value="glob">glob</option>
+<option value="regexp">regexp</option>
+<option value="query">query</option>
+</select>
+<input id="name" placeholder="name" autofocus>
+<input id="attrs" placeholder="key=pattern ...">
+<button>search</button>
+<span id="status" class="loc"></span>
+</form>
+<div id="main">
+<div><h2 id="title">Results</h2><ul id="results"></ul></div>
+<div id="files"><h2>Files</h2><ul id="file-list"></ul></div>
+</div>
+<script>
+(function() {
+  function $(id) { return document.getElementById(id); }
+
+  function get(path, params, f) {
+    var q = new URLSearchParams(params).toString();
+
+    fetch(path + "?" + q).then(function(r) { return r.json(); }).then(function(v) {
+      if (v && v.error) {
+        show("Error", [], v.error);
+        return;
+      }
+
+      f(v);
+    });
+  }
+
+  function el(tag, text, cls) {
+    var e = document.createElement(tag);
+    e.textContent = text;
+    if (cls) e.className = cls;
+    return e;
+  }
+
+  // show lists records, each linking to the records it resolves to.
+  function show(title, recs, error) {
+    $("title").textContent = title;
+
+    var ul = $("results");
+    ul.innerHTML = "";
+
+    if (error) {
+      ul.appendChild(el("li", error, "error"));
+      return;
+    }
+
+    (recs || []).forEach(function(r) {
+      var li = el("li", "");
+
+      var a = el("a", r.name);
+      a.onclick = function() {
+        get("/api/resolve", {loc: r.path + ":" + r.line + "." + r.column}, function(v) { show("Uses of " + r.name, v); });
+      };
+
+      li.appendChild(a);
+      li.appendChild(el("span", " " + r.loc, "loc"));
+
+      var attrs = Object.keys(r.attrs || {}).sort().map(function(k) { return r.attrs[k] ? k + "=" + r.attrs[k] : k; });
+      if (attrs.length) li.appendChild(el("span", " " + attrs.join(" "), "attrs"));
+
+      ul.appendChild(li);
+    });
+  }
+
+  $("search").onsubmit = function(e) {
+    e.preventDefault();
+
+    var params = {type: $("type").value, name: $("name").value};
+
+    $("attrs").value.split(/\s+/).filter(Boolean).forEach(function(kv) {
+      var i = kv.indexOf("=");
+      if (i < 0) params[kv] = ""; else params[kv.slice(0, i)] = kv.slice(i + 1);
+    });
+
+    get("/api/search", params, function(v) { show("Results", v); });
+  };
+
+  get("/api/files", {}, function(files) {
+    files.forEach(function(f) {
+      var li = el("li", "");
+      var a = el("a", f.path);
+      a.onclick = function() { get("/api/file", {path: f.path}, function(v) { show(f.path, v); }); };
+      li.appendChild(a);
+      li.appendChild(el("span", " " + f.count, "loc"));
+      $("file-list").appendChild(li);
+    });
+  });
+
+  get("/api/status", {}, function(s) { $("status").textContent = s.entries + " tags, updated " + s.updated; });
+})();
+</script>
+</body>
+</html>
+`
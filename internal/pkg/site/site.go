@@ -0,0 +1,488 @@
+// Package site generates a static HTML site for browsing the index: a page
+// per tag name with the source around each of its occurrences, a page per
+// file, an attribute index, and a search page. Pages are self-contained and
+// work offline.
+package site
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+type Options struct {
+	Context int // lines of source shown around each occurrence.
+
+	// Bracket is escaped in pages, so that the tags they quote are not
+	// scanned again if the site is in the scanned tree.
+	Bracket scanner.BracketConfig
+}
+
+type attr struct{ Key, Value string }
+
+type snippetLine struct {
+	Number             int
+	Before, Tag, After string // Tag is the part of the line within the tag.
+}
+
+type occurrence struct {
+	N          int // 1-based, in the order of the tag page.
+	Name       string
+	Loc        string
+	Path       string
+	Line       int
+	Attrs      []attr
+	Snippet    []snippetLine // nil if the source could not be read.
+	Prev, Next int           // cyclic, as in resolve.
+
+	ent *index.Entry
+}
+
+type searchTag struct {
+	Text    string
+	Path    string
+	Line    int
+	Loc     string
+	Matches []string // names of matched tags.
+}
+
+type tagPage struct {
+	Name      string
+	Occs      []*occurrence
+	MatchedBy []*searchTag
+}
+
+type filePage struct {
+	Path     string
+	Occs     []*occurrence
+	Searches []*searchTag
+}
+
+type attrValue struct {
+	Value string
+	Names []string
+}
+
+type attrFacet struct {
+	Key    string
+	Values []*attrValue
+}
+
+type searchItem struct {
+	Kind, Label, Href string
+	Text              string // what the item is searched by.
+}
+
+type site struct {
+	Tags   []*tagPage
+	Files  []*filePage
+	Facets []*attrFacet
+	Items  []searchItem
+}
+
+// Generate writes the site for idx into dir, which is created if needed.
+// Source files are read using readFile.
+func Generate(z *zlog.Logger, idx *index.Index, dir string, opts Options, readFile func(string) ([]byte, error)) error {
+	s := build(z, idx, opts, readFile)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err // do not wrap
+	}
+
+	write := func(name, tmpl string, data interface{}) error {
+		var b bytes.Buffer
+
+		if err := templates.ExecuteTemplate(&b, tmpl, data); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		return ioutil.WriteFile(filepath.Join(dir, name), escapeBrackets(b.Bytes(), opts.Bracket), 0644)
+	}
+
+	if err := write("index.html", "index", s); err != nil {
+		return err
+	}
+
+	if err := write("attrs.html", "attrs", s.Facets); err != nil {
+		return err
+	}
+
+	for _, p := range s.Tags {
+		if err := write(tagPageName(p.Name), "tag", p); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range s.Files {
+		if err := write(filePageName(p.Path), "file", p); err != nil {
+			return err
+		}
+	}
+
+	z.Infow("site generated", "dir", dir, "tags", len(s.Tags), "files", len(s.Files))
+
+	return nil
+}
+
+func build(z *zlog.Logger, idx *index.Index, opts Options, readFile func(string) ([]byte, error)) *site {
+	var (
+		s = &site{}
+
+		tags     = make(map[string]*tagPage)
+		files    = make(map[string]*filePage)
+		facets   = make(map[string]map[string]map[string]bool) // key -> value -> names.
+		ents     []*index.Entry
+		searches []*index.Entry
+	)
+
+	file := func(path string) *filePage {
+		p := files[path]
+		if p == nil {
+			p = &filePage{Path: path}
+			files[path] = p
+			s.Files = append(s.Files, p)
+		}
+
+		return p
+	}
+
+	sources := newSources(z, readFile)
+
+	// index order is by name, then by loc.
+	_ = index.ForEach(idx, func(ent *index.Entry) error {
+		if _, search := ent.IsSearch(); search {
+			searches = append(searches, ent)
+		} else {
+			ents = append(ents, ent)
+		}
+
+		return nil
+	})
+
+	for _, ent := range ents {
+		p := tags[ent.Name]
+		if p == nil {
+			p = &tagPage{Name: ent.Name}
+			tags[ent.Name] = p
+			s.Tags = append(s.Tags, p)
+		}
+
+		occ := &occurrence{
+			N:       len(p.Occs) + 1,
+			Name:    ent.Name,
+			Loc:     ent.Loc.String(),
+			Path:    ent.Loc.Path,
+			Line:    ent.Loc.Line,
+			Attrs:   sortedAttrs(ent.Attrs),
+			Snippet: sources.snippet(ent, opts.Context),
+			ent:     ent,
+		}
+
+		p.Occs = append(p.Occs, occ)
+
+		fp := file(ent.Loc.Path)
+		fp.Occs = append(fp.Occs, occ)
+
+		for _, a := range occ.Attrs {
+			if facets[a.Key] == nil {
+				facets[a.Key] = make(map[string]map[string]bool)
+			}
+
+			if facets[a.Key][a.Value] == nil {
+				facets[a.Key][a.Value] = make(map[string]bool)
+			}
+
+			facets[a.Key][a.Value][ent.Name] = true
+		}
+	}
+
+	for _, p := range s.Tags {
+		for i, occ := range p.Occs {
+			occ.Prev = (i+len(p.Occs)-1)%len(p.Occs) + 1
+			occ.Next = (i+1)%len(p.Occs) + 1
+		}
+	}
+
+	for _, ent := range searches {
+		st := &searchTag{
+			Text: searchText(ent),
+			Path: ent.Loc.Path,
+			Line: ent.Loc.Line,
+			Loc:  ent.Loc.String(),
+		}
+
+		if m, err := ent.Matcher(); err != nil {
+			z.Warnw("invalid search tag", "loc", ent.Loc, "err", err)
+		} else {
+			for _, p := range s.Tags {
+				for _, occ := range p.Occs {
+					if m(occ.ent) {
+						st.Matches = append(st.Matches, p.Name)
+						p.MatchedBy = append(p.MatchedBy, st)
+
+						break
+					}
+				}
+			}
+		}
+
+		fp := file(ent.Loc.Path)
+		fp.Searches = append(fp.Searches, st)
+	}
+
+	sort.Slice(s.Files, func(i, j int) bool { return s.Files[i].Path < s.Files[j].Path })
+
+	for _, fp := range s.Files {
+		sort.SliceStable(fp.Occs, func(i, j int) bool { return fp.Occs[i].Line < fp.Occs[j].Line })
+	}
+
+	keys := make([]string, 0, len(facets))
+	for k := range facets {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		f := &attrFacet{Key: k}
+
+		for v, names := range facets[k] {
+			av := &attrValue{Value: v}
+			for name := range names {
+				av.Names = append(av.Names, name)
+			}
+
+			sort.Strings(av.Names)
+
+			f.Values = append(f.Values, av)
+		}
+
+		sort.Slice(f.Values, func(i, j int) bool { return f.Values[i].Value < f.Values[j].Value })
+
+		s.Facets = append(s.Facets, f)
+	}
+
+	// tags are searched by their name and the attributes of all their
+	// occurrences.
+	for _, p := range s.Tags {
+		texts := []string{p.Name}
+
+		seen := make(map[string]bool)
+
+		for _, occ := range p.Occs {
+			for _, a := range occ.Attrs {
+				if text := index.AttrToString(a.Key, a.Value); !seen[text] {
+					seen[text] = true
+					texts = append(texts, text)
+				}
+			}
+		}
+
+		text := strings.Join(texts, " ")
+
+		s.Items = append(s.Items, searchItem{Kind: "tag", Label: p.Name, Text: text, Href: tagPageName(p.Name)})
+	}
+
+	for _, p := range s.Files {
+		s.Items = append(s.Items, searchItem{Kind: "file", Label: p.Path, Text: p.Path, Href: filePageName(p.Path)})
+	}
+
+	return s
+}
+
+func sortedAttrs(attrs index.Attrs) []attr {
+	as := make([]attr, 0, len(attrs))
+	for k, v := range attrs {
+		as = append(as, attr{Key: k, Value: v})
+	}
+
+	sort.Slice(as, func(i, j int) bool { return as[i].Key < as[j].Key })
+
+	return as
+}
+
+func searchText(ent *index.Entry) string {
+	pt, _ := ent.IsSearch()
+
+	parts := []string{"?" + pt, ent.Name}
+
+	for _, a := range sortedAttrs(ent.Attrs) {
+		if a.Key != "search" {
+			parts = append(parts, index.AttrToString(a.Key, a.Value))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func tagPageName(name string) string  { return "tag-" + pageName(name) + ".html" }
+func filePageName(path string) string { return "file-" + pageName(path) + ".html" }
+
+// maxPageName is the length of escaped names above which they are
+// truncated, keeping page file names well within file system limits.
+const maxPageName = 200
+
+// pageName returns s escaped by escapeName. Names too long for a file name
+// are truncated, and suffixed by a short hash of s to keep them distinct.
+func pageName(s string) string {
+	esc := escapeName(s)
+	if len(esc) <= maxPageName {
+		return esc
+	}
+
+	sum := sha1.Sum([]byte(s))
+	hash := hex.EncodeToString(sum[:])[:12]
+
+	esc = esc[:maxPageName-len(hash)-1]
+
+	// do not leave a partial escape.
+	if i := strings.LastIndexByte(esc, '~'); i >= len(esc)-2 {
+		esc = esc[:i]
+	}
+
+	return esc + "-" + hash
+}
+
+// escapeName returns s with all bytes but lower case letters, digits, "-",
+// "_" and "." escaped as "~" and their hex value, so distinct names have
+// distinct file names, even on case insensitive file systems.
+func escapeName(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "~%02x", c)
+		}
+	}
+
+	return b.String()
+}
+
+// sources reads source files once each.
+type sources struct {
+	z        *zlog.Logger
+	readFile func(string) ([]byte, error)
+	lines    map[string][]string // nil if the file could not be read.
+}
+
+func newSources(z *zlog.Logger, readFile func(string) ([]byte, error)) *sources {
+	return &sources{z: z, readFile: readFile, lines: make(map[string][]string)}
+}
+
+func (s *sources) file(path string) []string {
+	lines, ok := s.lines[path]
+	if ok {
+		return lines
+	}
+
+	bs, err := s.readFile(path)
+	if err != nil {
+		s.z.Warnw("cannot read source", "path", path, "err", err)
+	} else {
+		lines = strings.Split(strings.TrimSuffix(string(bs), "\n"), "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimSuffix(l, "\r")
+		}
+	}
+
+	s.lines[path] = lines
+
+	return lines
+}
+
+// escapeBrackets replaces the brackets in a page with character references,
+// which are displayed the same.
+func escapeBrackets(bs []byte, bracket scanner.BracketConfig) []byte {
+	for _, br := range []string{bracket.Left, bracket.Right} {
+		if br == "" {
+			continue
+		}
+
+		var ref strings.Builder
+		for _, r := range br {
+			fmt.Fprintf(&ref, "&#%d;", r)
+		}
+
+		bs = bytes.ReplaceAll(bs, []byte(br), []byte(ref.String()))
+	}
+
+	return bs
+}
+
+// snippet returns the lines of ent and n lines around it, with the tag
+// marked. Columns are byte offsets, 1-based.
+func (s *sources) snippet(ent *index.Entry, n int) []snippetLine {
+	lines := s.file(ent.Loc.Path)
+	if lines == nil {
+		return nil
+	}
+
+	first, last := ent.Loc.Line, ent.Loc.LastLine()
+
+	if first < 1 || last > len(lines) {
+		s.z.Warnw("loc out of range, index might be out of date", "loc", ent.Loc)
+		return nil
+	}
+
+	from, to := first-n, last+n
+	if from < 1 {
+		from = 1
+	}
+
+	if to > len(lines) {
+		to = len(lines)
+	}
+
+	snippet := make([]snippetLine, 0, to-from+1)
+
+	for i := from; i <= to; i++ {
+		text := lines[i-1]
+
+		sl := snippetLine{Number: i, Before: text}
+
+		if i >= first && i <= last {
+			start, end := 0, len(text)
+
+			if i == first {
+				start = clamp(ent.Loc.StartColumn-1, 0, len(text))
+			}
+
+			if i == last {
+				end = clamp(ent.Loc.EndColumn, start, len(text))
+			}
+
+			sl.Before, sl.Tag, sl.After = text[:start], text[start:end], text[end:]
+		}
+
+		snippet = append(snippet, sl)
+	}
+
+	return snippet
+}
+
+func clamp(x, min, max int) int {
+	if x < min {
+		return min
+	}
+
+	if x > max {
+		return max
+	}
+
+	return x
+}
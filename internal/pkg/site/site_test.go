@@ -0,0 +1,187 @@
+// [# %stop! #]
+
+package site
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+var (
+	testFiles = map[string]string{
+		"a.go": "package a\n\n// [# cat see=Dog #]\nfunc a() {}\n",
+		"b.py": "# [# cat\n#  lang=py #] [# ?g c* #]\n",
+	}
+
+	testIndex = index.NewIndex([]*index.Entry{
+		{Name: "cat", Attrs: index.Attrs{"see": "Dog"}, Loc: scanner.Loc{Path: "a.go", Line: 3, StartColumn: 4, EndColumn: 20}},
+		{Name: "cat", Attrs: index.Attrs{"lang": "py"}, Loc: scanner.Loc{Path: "b.py", Line: 1, StartColumn: 3, EndLine: 2, EndColumn: 13}},
+		{Name: "c*", Attrs: index.Attrs{"search": "glob"}, Loc: scanner.Loc{Path: "b.py", Line: 2, StartColumn: 15, EndColumn: 26}},
+		{Name: "Dog", Loc: scanner.Loc{Path: "c.go", Line: 1, StartColumn: 1, EndColumn: 10}},
+	})
+)
+
+func readTestFile(path string) ([]byte, error) {
+	text, ok := testFiles[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	return []byte(text), nil
+}
+
+func TestBuild(t *testing.T) {
+	s := build(zlog.NewNopLogger(), testIndex, Options{Context: 1}, readTestFile)
+
+	if len(s.Tags) != 2 || s.Tags[0].Name != "Dog" || s.Tags[1].Name != "cat" {
+		t.Fatalf("tags: %+v", s.Tags)
+	}
+
+	cat := s.Tags[1]
+
+	if len(cat.Occs) != 2 {
+		t.Fatalf("occurrences: %+v", cat.Occs)
+	}
+
+	a, b := cat.Occs[0], cat.Occs[1]
+
+	if a.Prev != 2 || a.Next != 2 || b.Prev != 1 || b.Next != 1 {
+		t.Errorf("prev/next: %+v %+v", a, b)
+	}
+
+	if exp := []snippetLine{
+		{Number: 2},
+		{Number: 3, Before: "// ", Tag: "[# cat see=Dog #]"},
+		{Number: 4, Before: "func a() {}"},
+	}; !reflect.DeepEqual(a.Snippet, exp) {
+		t.Errorf("snippet: %+v != %+v", a.Snippet, exp)
+	}
+
+	if exp := []snippetLine{
+		{Number: 1, Before: "# ", Tag: "[# cat"},
+		{Number: 2, Tag: "#  lang=py #]", After: " [# ?g c* #]"},
+	}; !reflect.DeepEqual(b.Snippet, exp) {
+		t.Errorf("multi-line snippet: %+v != %+v", b.Snippet, exp)
+	}
+
+	if s.Tags[0].Occs[0].Snippet != nil {
+		t.Errorf("snippet of missing file: %+v", s.Tags[0].Occs[0].Snippet)
+	}
+
+	if len(cat.MatchedBy) != 1 || !reflect.DeepEqual(cat.MatchedBy[0].Matches, []string{"cat"}) {
+		t.Errorf("matched by: %+v", cat.MatchedBy)
+	}
+
+	var keys []string
+	for _, f := range s.Facets {
+		keys = append(keys, f.Key)
+	}
+
+	if exp := []string{"lang", "see"}; !reflect.DeepEqual(keys, exp) {
+		t.Errorf("facets: %v != %v", keys, exp)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clutter-site")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	bracket := scanner.BracketConfig{
+		Left:  "[#",
+		Right: "#]",
+	}
+
+	if err := Generate(zlog.NewNopLogger(), testIndex, dir, Options{Bracket: bracket}, readTestFile); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		page     string
+		contains []string
+	}{
+		{"index.html", []string{`href="tag-~44og.html"`, `data-text="cat see=Dog lang=py"`, `href="file-b.py.html"`}},
+		{"tag-cat.html", []string{`id="o2"`, `<a href="#o1">previous</a>`, "<mark>&#91;&#35; cat see=Dog &#35;&#93;</mark>", `href="file-b.py.html#l2"`}},
+		{"file-b.py.html", []string{`href="tag-cat.html#o2"`, "<code>?glob c*</code>"}},
+		{"attrs.html", []string{`<code>Dog</code>: <a href="tag-cat.html">cat</a>`}},
+	}
+
+	for _, test := range tests {
+		bs, err := ioutil.ReadFile(filepath.Join(dir, test.page))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, s := range test.contains {
+			if !strings.Contains(string(bs), s) {
+				t.Errorf("%s: %q not found", test.page, s)
+			}
+		}
+
+		if strings.Contains(string(bs), bracket.Left) {
+			t.Errorf("%s: bracket not escaped", test.page)
+		}
+	}
+}
+
+func TestEscapeName(t *testing.T) {
+	names := []string{"a", "A", "a/b", "a~2fb", "a_b", "a.b", "a b"}
+
+	seen := make(map[string]string)
+
+	for _, name := range names {
+		esc := strings.ToLower(escapeName(name))
+
+		if other, ok := seen[esc]; ok {
+			t.Errorf("%q and %q are both escaped as %q", name, other, esc)
+		}
+
+		seen[esc] = name
+	}
+}
+
+func TestLongPageName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clutter-site")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	long := strings.Repeat("Deep/", 60)
+
+	idx := index.NewIndex([]*index.Entry{
+		{Name: "a", Loc: scanner.Loc{Path: long + "x.go", Line: 1, StartColumn: 1, EndColumn: 10}},
+		{Name: "a", Loc: scanner.Loc{Path: long + "y.go", Line: 1, StartColumn: 1, EndColumn: 10}},
+	})
+
+	x, y := filePageName(long+"x.go"), filePageName(long+"y.go")
+
+	if x == y {
+		t.Errorf("both pages are named %q", x)
+	}
+
+	if len(x) > 255 {
+		t.Errorf("%q is too long", x)
+	}
+
+	if err := Generate(zlog.NewNopLogger(), idx, dir, Options{}, readTestFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, y)); err != nil {
+		t.Error(err)
+	}
+}
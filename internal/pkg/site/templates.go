@@ -0,0 +1,105 @@
+package site
+
+import (
+	"html/template"
+)
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"tagHref":  tagPageName,
+	"fileHref": filePageName,
+}).Parse(templatesText))
+
+// the style and script are inline, so pages work offline and when opened
+// from the file system.
+const templatesText = `
+{{define "header"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}} - clutter</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+a { color: #0645ad; text-decoration: none; }
+a:hover { text-decoration: underline; }
+nav { margin-bottom: 1em; }
+code, pre { font-family: monospace; }
+.occ { margin-bottom: 1.5em; }
+.attrs { color: #555; }
+pre.snippet { background: #f6f8fa; padding: 0.5em; overflow-x: auto; }
+.lineno { color: #999; user-select: none; display: inline-block; min-width: 4em; }
+mark { background: #fff3a0; }
+ul.results li.hidden { display: none; }
+input#q { font-size: 1.1em; width: 30em; max-width: 100%; }
+</style>
+</head>
+<body>
+<nav><a href="index.html">tags</a> | <a href="attrs.html">attributes</a></nav>
+{{end}}
+
+{{define "footer"}}</body>
+</html>
+{{end}}
+
+{{define "attrs-list"}}{{range .}} <span class="attrs">{{.Key}}{{if .Value}}={{.Value}}{{end}}</span>{{end}}{{end}}
+
+{{define "index"}}{{template "header" "index"}}
+<h1>Tags</h1>
+<input id="q" type="search" placeholder="search tags, attributes and files" autofocus>
+<ul class="results" id="results">
+{{range .Items}}<li data-text="{{.Text}}"><a href="{{.Href}}">{{.Label}}</a> <small>{{.Kind}}</small></li>
+{{end}}</ul>
+<script>
+(function() {
+  var q = document.getElementById("q");
+  var items = document.getElementById("results").getElementsByTagName("li");
+
+  q.addEventListener("input", function() {
+    var words = q.value.toLowerCase().split(/\s+/).filter(Boolean);
+
+    for (var i = 0; i < items.length; i++) {
+      var text = items[i].getAttribute("data-text").toLowerCase();
+      var match = words.every(function(w) { return text.indexOf(w) >= 0; });
+
+      items[i].className = match ? "" : "hidden";
+    }
+  });
+})();
+</script>
+{{template "footer"}}{{end}}
+
+{{define "tag"}}{{template "header" .Name}}
+<h1>{{.Name}}</h1>
+<p>{{len .Occs}} occurrence(s).</p>
+{{range .Occs}}<div class="occ" id="o{{.N}}">
+<h3><a href="{{fileHref .Path}}#l{{.Line}}">{{.Loc}}</a>{{template "attrs-list" .Attrs}}</h3>
+<small><a href="#o{{.Prev}}">previous</a> | <a href="#o{{.Next}}">next</a></small>
+{{if .Snippet}}<pre class="snippet">{{range .Snippet}}<span class="lineno">{{.Number}}</span>{{.Before}}{{if .Tag}}<mark>{{.Tag}}</mark>{{end}}{{.After}}
+{{end}}</pre>{{end}}
+</div>
+{{end}}
+{{if .MatchedBy}}<h2>Matched by</h2>
+<ul>
+{{range .MatchedBy}}<li><a href="{{fileHref .Path}}#l{{.Line}}">{{.Loc}}</a> <code>{{.Text}}</code></li>
+{{end}}</ul>{{end}}
+{{template "footer"}}{{end}}
+
+{{define "file"}}{{template "header" .Path}}
+<h1>{{.Path}}</h1>
+<ul>
+{{range .Occs}}<li id="l{{.Line}}"><a href="{{tagHref .Name}}#o{{.N}}">{{.Name}}</a> <small>{{.Loc}}</small>{{template "attrs-list" .Attrs}}</li>
+{{end}}</ul>
+{{if .Searches}}<h2>Searches</h2>
+<ul>
+{{range .Searches}}<li id="l{{.Line}}"><code>{{.Text}}</code> <small>{{.Loc}}</small>{{range .Matches}} <a href="{{tagHref .}}">{{.}}</a>{{end}}</li>
+{{end}}</ul>{{end}}
+{{template "footer"}}{{end}}
+
+{{define "attrs"}}{{template "header" "attributes"}}
+<h1>Attributes</h1>
+{{range .}}<h2 id="{{.Key}}">{{.Key}}</h2>
+<ul>
+{{range .Values}}<li>{{if .Value}}<code>{{.Value}}</code>{{else}}<em>no value</em>{{end}}:{{range .Names}} <a href="{{tagHref .}}">{{.}}</a>{{end}}</li>
+{{end}}</ul>
+{{end}}
+{{template "footer"}}{{end}}
+`
@@ -0,0 +1,182 @@
+// Package tagsfile writes the index as tags files that editors with native
+// tag support can read: ctags for Vim, and etags for Emacs.
+package tagsfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+)
+
+const (
+	FormatCtags = "ctags"
+	FormatEtags = "etags"
+)
+
+// Write writes idx to w in format. readFile is used to read the source
+// files, which etags requires.
+func Write(w io.Writer, format string, idx *index.Index, readFile func(string) ([]byte, error)) error {
+	ents := entries(idx)
+
+	switch format {
+	case FormatCtags:
+		return writeCtags(w, ents)
+	case FormatEtags:
+		return writeEtags(w, ents, readFile)
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// entries returns all entries of idx but search entries, as their names are
+// patterns rather than names to jump to.
+func entries(idx *index.Index) []*index.Entry {
+	var ents []*index.Entry
+
+	_ = index.ForEach(idx, func(ent *index.Entry) error {
+		if _, search := ent.IsSearch(); !search {
+			ents = append(ents, ent)
+		}
+
+		return nil
+	})
+
+	return ents
+}
+
+var ctagsHeader = []string{
+	"!_TAG_FILE_FORMAT\t2\t/extended format; --format=1 will not append ;\" to lines/",
+	"!_TAG_FILE_SORTED\t1\t/0=unsorted, 1=sorted, 2=foldcase/",
+	"!_TAG_PROGRAM_NAME\tclutter\t//",
+	"!_TAG_PROGRAM_URL\thttps://github.com/cluttercode/clutter\t//",
+}
+
+// writeCtags writes ents in the Universal Ctags extended format, sorted by
+// name. Tags are addressed by line number, and their attributes are written
+// as extension fields named by ctagsAttrPrefix.
+func writeCtags(w io.Writer, ents []*index.Entry) error {
+	ents = append([]*index.Entry(nil), ents...)
+
+	sort.SliceStable(ents, func(i, j int) bool {
+		a, b := ents[i], ents[j]
+
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+
+		if a.Loc.Path != b.Loc.Path {
+			return a.Loc.Path < b.Loc.Path
+		}
+
+		return a.Loc.Line < b.Loc.Line
+	})
+
+	bw := bufio.NewWriter(w)
+
+	for _, h := range ctagsHeader {
+		fmt.Fprintln(bw, h)
+	}
+
+	for _, ent := range ents {
+		fmt.Fprintf(bw, "%s\t%s\t%d;\"\tkind:tag\tline:%d", ent.Name, ent.Loc.Path, ent.Loc.Line, ent.Loc.Line)
+
+		keys := make([]string, 0, len(ent.Attrs))
+		for k := range ent.Attrs {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			fmt.Fprintf(bw, "\t%s%s:%s", ctagsAttrPrefix, k, escapeCtagsField(ent.Attrs[k]))
+		}
+
+		fmt.Fprintln(bw)
+	}
+
+	return bw.Flush()
+}
+
+// ctagsAttrPrefix prefixes the names of attribute fields, so they do not
+// clash with the fields ctags defines, such as scope, kind and line.
+const ctagsAttrPrefix = "attr_"
+
+var ctagsFieldEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+func escapeCtagsField(v string) string { return ctagsFieldEscaper.Replace(v) }
+
+// writeEtags writes ents in the etags format, with a section per file, in
+// order of first appearance in ents. Each tag is located by the text of its
+// first line up to its end, and by its line and the byte offset of it.
+func writeEtags(w io.Writer, ents []*index.Entry, readFile func(string) ([]byte, error)) error {
+	var paths []string
+
+	byPath := make(map[string][]*index.Entry)
+
+	for _, ent := range ents {
+		if byPath[ent.Loc.Path] == nil {
+			paths = append(paths, ent.Loc.Path)
+		}
+
+		byPath[ent.Loc.Path] = append(byPath[ent.Loc.Path], ent)
+	}
+
+	bw := bufio.NewWriter(w)
+
+	for _, path := range paths {
+		bs, err := readFile(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		lines := lineOffsets(bs)
+
+		var section bytes.Buffer
+
+		for _, ent := range byPath[path] {
+			n := ent.Loc.Line
+			if n < 1 || n > len(lines) {
+				return fmt.Errorf("%v: line out of range, index might be out of date", ent.Loc)
+			}
+
+			line := bs[lines[n-1]:]
+			if nl := bytes.IndexByte(line, '\n'); nl >= 0 {
+				line = line[:nl]
+			}
+
+			// the text up to the end of a single line tag, or the whole first
+			// line of a multi-line one.
+			if ent.Loc.LastLine() == n && ent.Loc.EndColumn <= len(line) {
+				line = line[:ent.Loc.EndColumn]
+			}
+
+			fmt.Fprintf(&section, "%s\x7f%s\x01%d,%d\n", bytes.TrimRight(line, "\r"), ent.Name, n, lines[n-1])
+		}
+
+		fmt.Fprintf(bw, "\x0c\n%s,%d\n", path, section.Len())
+
+		if _, err := section.WriteTo(bw); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// lineOffsets returns the byte offsets in bs of the start of each line.
+func lineOffsets(bs []byte) []int {
+	offsets := []int{0}
+
+	for i, b := range bs {
+		if b == '\n' && i+1 < len(bs) {
+			offsets = append(offsets, i+1)
+		}
+	}
+
+	return offsets
+}
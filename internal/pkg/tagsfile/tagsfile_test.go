@@ -0,0 +1,76 @@
+// [# %stop! #]
+
+package tagsfile
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+)
+
+func TestWrite(t *testing.T) {
+	files := map[string]string{
+		"a.go": "package a\n\n// [# meow lang=go #] [# woof #]\n",
+		"b.py": "# [# meow\n#  who=\"zumi\\tmidnight\" #]\n",
+	}
+
+	readFile := func(path string) ([]byte, error) {
+		text, ok := files[path]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+
+		return []byte(text), nil
+	}
+
+	idx := index.NewIndex([]*index.Entry{
+		{Name: "woof", Attrs: index.Attrs{"scope": "a.go"}, Loc: scanner.Loc{Path: "a.go", Line: 3, StartColumn: 23, EndColumn: 32}},
+		{Name: "meow", Attrs: index.Attrs{"lang": "go"}, Loc: scanner.Loc{Path: "a.go", Line: 3, StartColumn: 4, EndColumn: 21}},
+		{Name: "meow", Attrs: index.Attrs{"who": "zumi\tmidnight"}, Loc: scanner.Loc{Path: "b.py", Line: 1, StartColumn: 3, EndLine: 2, EndColumn: 26}},
+		{Name: "m*", Attrs: index.Attrs{"search": "glob"}, Loc: scanner.Loc{Path: "c.go", Line: 1, StartColumn: 1, EndColumn: 10}},
+	})
+
+	tests := []struct {
+		format, exp string
+	}{
+		{
+			format: FormatCtags,
+			exp: "!_TAG_FILE_FORMAT\t2\t/extended format; --format=1 will not append ;\" to lines/\n" +
+				"!_TAG_FILE_SORTED\t1\t/0=unsorted, 1=sorted, 2=foldcase/\n" +
+				"!_TAG_PROGRAM_NAME\tclutter\t//\n" +
+				"!_TAG_PROGRAM_URL\thttps://github.com/cluttercode/clutter\t//\n" +
+				"meow\ta.go\t3;\"\tkind:tag\tline:3\tattr_lang:go\n" +
+				"meow\tb.py\t1;\"\tkind:tag\tline:1\tattr_who:zumi\\tmidnight\n" +
+				"woof\ta.go\t3;\"\tkind:tag\tline:3\tattr_scope:a.go\n",
+		},
+		{
+			format: FormatEtags,
+			exp: "\x0c\na.go,75\n" +
+				"// [# meow lang=go #]\x7fmeow\x013,11\n" +
+				"// [# meow lang=go #] [# woof #]\x7fwoof\x013,11\n" +
+				"\x0c\nb.py,19\n" +
+				"# [# meow\x7fmeow\x011,0\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var b bytes.Buffer
+
+			if err := Write(&b, test.format, idx, readFile); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := b.String(); got != test.exp {
+				t.Errorf("\n%q\n!=\n%q", got, test.exp)
+			}
+		})
+	}
+
+	if err := Write(&bytes.Buffer{}, "nosuchformat", idx, readFile); err == nil {
+		t.Errorf("error expected, but got nil")
+	}
+}
@@ -0,0 +1,89 @@
+// [# %stop! #]
+
+package clutter
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTree(t *testing.T) {
+	dir, err := ioutil.TempDir("", "clutter-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("// [# a x=1 #]\n// [# b\n#]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	elems, err := ScanTree(ScanConfig{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ents, err := ParseElements(elems)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "index")
+
+	if err := WriteIndex(path, NewIndex(ents)); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := ReadIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := idx.Entries()
+
+	exp := []*Entry{
+		{Name: "a", Attrs: map[string]string{"x": "1"}, Loc: Loc{Path: filepath.Join(dir, "a.go"), Line: 1, Column: 4, EndLine: 1, EndColumn: 14}},
+		{Name: "b", Attrs: map[string]string{}, Loc: Loc{Path: filepath.Join(dir, "a.go"), Line: 2, Column: 4, EndLine: 3, EndColumn: 2}},
+	}
+
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("%+v != %+v", got, exp)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var (
+		parseErr  *ParseError
+		indexErr  *IndexError
+		configErr *ConfigError
+	)
+
+	if _, err := ParseElement(&Element{Text: "a *", Loc: Loc{Path: "x", Line: 1}}); !errors.As(err, &parseErr) || parseErr.Loc.Path != "x" {
+		t.Errorf("expected parse error, got %v", err)
+	}
+
+	if _, err := ReadIndex("/nonexistent/index"); !errors.As(err, &indexErr) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected missing index error, got %v", err)
+	}
+
+	if _, err := NewMatcher(MatchRegexp, "(", nil); !errors.As(err, &configErr) {
+		t.Errorf("expected config error, got %v", err)
+	}
+
+	if _, err := ScanTree(ScanConfig{Left: "<<"}, "."); !errors.As(err, &configErr) {
+		t.Errorf("expected config error, got %v", err)
+	}
+
+	if _, err := ParseLintConfig([]byte("rules: [{name: x}]")); !errors.As(err, &configErr) {
+		t.Errorf("expected config error, got %v", err)
+	}
+
+	if _, err := Resolve(NewIndex(nil), Loc{Path: "x", Line: 1, Column: 1}, ResolveOptions{}); err != ErrNoTag {
+		t.Errorf("expected no tag, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+// Package clutter is the Go API of clutter. It scans trees and readers for
+// tags, parses them, reads, writes and merges indexes, searches and resolves
+// tags, and lints them, as the clutter command does.
+//
+// A typical use scans a tree, builds an index and searches it:
+//
+//	elems, err := clutter.ScanTree(clutter.ScanConfig{}, ".")
+//	...
+//	ents, err := clutter.ParseElements(elems)
+//	...
+//	m, err := clutter.NewMatcher(clutter.MatchGlob, "api-*", nil)
+//	...
+//	found := clutter.NewIndex(ents).Search(m)
+//
+// Errors are typed: scanning reports failing files as ScanErrors, parsing
+// reports a ParseError, reading an index reports an IndexError, and invalid
+// configurations and patterns are reported as ConfigErrors. ErrNoTag is
+// returned when resolving a location that has no tag. All of these can be
+// inspected with errors.As and errors.Is.
+//
+// Logging is optional. Functions that log take a *zlog.Logger in their
+// configuration, and log nothing if it is nil.
+//
+// Compatibility: this package follows semantic versioning. Within a major
+// version, exported identifiers are not removed or renamed, function
+// signatures do not change, and fields are only added to structs. Construct
+// structs using field names. The formats of the index and of lint
+// configurations are those of the clutter command, and change only when the
+// command's do. Everything under internal/ is not covered, and may change at
+// any time.
+package clutter
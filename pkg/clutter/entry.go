@@ -0,0 +1,116 @@
+package clutter
+
+import (
+	"fmt"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+)
+
+// Loc is the location of a tag. Lines and columns start at 1, and columns are
+// in bytes. EndColumn is the column right after the tag's closing bracket.
+type Loc struct {
+	Path      string
+	Line      int
+	Column    int
+	EndLine   int // same as Line if the tag is on a single line.
+	EndColumn int
+}
+
+// String formats l as path:line.col-endcol, or path:line.col-endline.endcol
+// for tags spanning multiple lines, as in indexes.
+func (l Loc) String() string { return l.internal().String() }
+
+// Contains returns true if other is within l.
+func (l Loc) Contains(other Loc) bool { return l.internal().Contains(other.internal()) }
+
+// ParseLoc parses a location in the format of Loc.String, or path:line.col.
+func ParseLoc(text string) (Loc, error) {
+	loc, err := scanner.ParseLocString(text)
+	if err != nil {
+		return Loc{}, &ConfigError{Err: fmt.Errorf("loc %q: %w", text, err)}
+	}
+
+	return newLoc(*loc), nil
+}
+
+func newLoc(l scanner.Loc) Loc {
+	return Loc{Path: l.Path, Line: l.Line, Column: l.StartColumn, EndLine: l.LastLine(), EndColumn: l.EndColumn}
+}
+
+func (l Loc) internal() scanner.Loc {
+	loc := scanner.Loc{Path: l.Path, Line: l.Line, StartColumn: l.Column, EndLine: l.EndLine, EndColumn: l.EndColumn}
+	if loc.EndLine <= loc.Line {
+		loc.EndLine = 0
+	}
+
+	return loc
+}
+
+// Element is a scanned tag that was not parsed yet.
+type Element struct {
+	Text string // without brackets.
+	Loc  Loc
+
+	raw *scanner.RawElement // if scanned, carries pragmas state.
+}
+
+func newElement(raw *scanner.RawElement) *Element {
+	return &Element{Text: raw.Text, Loc: newLoc(raw.Loc), raw: raw}
+}
+
+func (e *Element) internal() *scanner.RawElement {
+	raw := &scanner.RawElement{Text: e.Text, Loc: e.Loc.internal()}
+
+	if e.raw != nil {
+		raw.NoLint, raw.NoLintFile = e.raw.NoLint, e.raw.NoLintFile
+	}
+
+	return raw
+}
+
+// Entry is a parsed tag.
+type Entry struct {
+	Name  string
+	Attrs map[string]string
+	Loc   Loc
+
+	NoLint     []string // rules suppressed by the tag, with %nolint or its nolint attribute.
+	NoLintFile []string // rules suppressed by the tag's file, with %nolint-file.
+}
+
+// IsSearch returns the pattern type of a search tag, and whether e is one.
+func (e *Entry) IsSearch() (string, bool) {
+	t, ok := e.Attrs["search"]
+	return t, ok
+}
+
+// String formats e as an index line, without its suppressions.
+func (e *Entry) String() string { return e.internal().String() }
+
+func newEntry(ent *index.Entry) *Entry {
+	attrs := make(map[string]string, len(ent.Attrs))
+	for k, v := range ent.Attrs {
+		attrs[k] = v
+	}
+
+	return &Entry{Name: ent.Name, Attrs: attrs, Loc: newLoc(ent.Loc), NoLint: ent.NoLint, NoLintFile: ent.NoLintFile}
+}
+
+func newEntries(ents []*index.Entry) []*Entry {
+	pents := make([]*Entry, len(ents))
+	for i, ent := range ents {
+		pents[i] = newEntry(ent)
+	}
+
+	return pents
+}
+
+func (e *Entry) internal() *index.Entry {
+	attrs := make(index.Attrs, len(e.Attrs))
+	for k, v := range e.Attrs {
+		attrs[k] = v
+	}
+
+	return &index.Entry{Name: e.Name, Attrs: attrs, Loc: e.Loc.internal(), NoLint: e.NoLint, NoLintFile: e.NoLintFile}
+}
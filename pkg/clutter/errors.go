@@ -0,0 +1,68 @@
+package clutter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNoTag is returned when resolving a location that has no tag.
+var ErrNoTag = errors.New("no tag at loc")
+
+// ScanError is an error that occurred while scanning a specific file.
+type ScanError struct {
+	Path string
+	Err  error
+}
+
+func (e *ScanError) Error() string { return fmt.Sprintf("file %s: %v", e.Path, e.Err) }
+
+func (e *ScanError) Unwrap() error { return e.Err }
+
+// ScanErrors aggregates the errors of all files that failed to scan. It is
+// returned along with the elements of all other files.
+type ScanErrors []*ScanError
+
+func (es ScanErrors) Error() string {
+	if len(es) == 1 {
+		return es[0].Error()
+	}
+
+	msgs := make([]string, len(es))
+	for i, e := range es {
+		msgs[i] = e.Error()
+	}
+
+	return fmt.Sprintf("%d files failed:\n%s", len(es), strings.Join(msgs, "\n"))
+}
+
+// ParseError is an error parsing the text of a tag.
+type ParseError struct {
+	Text string // without brackets.
+	Loc  Loc
+	Err  error
+}
+
+func (e *ParseError) Error() string { return fmt.Sprintf("parse %q@%v: %v", e.Text, e.Loc, e.Err) }
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// IndexError is an error reading or writing an index file. Err wraps the
+// underlying error, so errors.Is(err, os.ErrNotExist) reports missing files.
+type IndexError struct {
+	Path string
+	Err  error
+}
+
+func (e *IndexError) Error() string { return fmt.Sprintf("index %s: %v", e.Path, e.Err) }
+
+func (e *IndexError) Unwrap() error { return e.Err }
+
+// ConfigError is an invalid configuration, pattern, query or lint rule.
+type ConfigError struct {
+	Err error
+}
+
+func (e *ConfigError) Error() string { return fmt.Sprintf("invalid config: %v", e.Err) }
+
+func (e *ConfigError) Unwrap() error { return e.Err }
@@ -0,0 +1,135 @@
+// [# %stop! #]
+
+package clutter_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cluttercode/clutter/pkg/clutter"
+)
+
+const src = `package auth
+
+// [# auth-flow owner=team-a #]
+func Login() {}
+
+// [# auth-flow #]
+func Logout() {}
+
+// [# ?gl "auth-*" #]
+`
+
+func scanAndParse() *clutter.Index {
+	elems, err := clutter.ScanReader(clutter.ScanConfig{}, "auth.go", strings.NewReader(src))
+	if err != nil {
+		panic(err)
+	}
+
+	ents, err := clutter.ParseElements(elems)
+	if err != nil {
+		panic(err)
+	}
+
+	return clutter.NewIndex(ents)
+}
+
+func Example() {
+	idx := scanAndParse()
+
+	for _, ent := range idx.Entries() {
+		fmt.Println(ent)
+	}
+
+	// Output:
+	// auth-* auth.go:9.4-21 search=glob
+	// auth-flow auth.go:3.4-31 owner=team-a
+	// auth-flow auth.go:6.4-18
+}
+
+func ExampleIndex_Search() {
+	idx := scanAndParse()
+
+	m, err := clutter.NewMatcher(clutter.MatchGlob, "auth-*", map[string]string{"owner": "*"})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, ent := range idx.Search(m) {
+		fmt.Println(ent.Name, ent.Loc)
+	}
+
+	// Output:
+	// auth-flow auth.go:3.4-31
+}
+
+func ExampleResolve() {
+	idx := scanAndParse()
+
+	ents, err := clutter.Resolve(idx, clutter.Loc{Path: "auth.go", Line: 3, Column: 5}, clutter.ResolveOptions{Next: true})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, ent := range ents {
+		fmt.Println(ent.Loc)
+	}
+
+	if _, err := clutter.Resolve(idx, clutter.Loc{Path: "auth.go", Line: 4, Column: 1}, clutter.ResolveOptions{}); err == clutter.ErrNoTag {
+		fmt.Println(err)
+	}
+
+	// Output:
+	// auth.go:6.4-18
+	// no tag at loc
+}
+
+func ExampleIndex_MergeRepo() {
+	backend := scanAndParse()
+
+	docs := clutter.NewIndex([]*clutter.Entry{
+		{Name: "auth-flow", Attrs: map[string]string{"repo": "backend"}, Loc: clutter.Loc{Path: "README.md", Line: 1, Column: 1, EndLine: 1, EndColumn: 30}},
+	})
+
+	ents, err := clutter.Resolve(docs.MergeRepo("backend", "", backend), clutter.Loc{Path: "README.md", Line: 1, Column: 1}, clutter.ResolveOptions{})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, ent := range ents {
+		fmt.Println(ent.Loc)
+	}
+
+	// Output:
+	// @backend/auth.go:3.4-31
+	// @backend/auth.go:6.4-18
+	// README.md:1.1-30
+}
+
+func ExampleLint() {
+	idx := scanAndParse()
+
+	cfg, err := clutter.ParseLintConfig([]byte(`
+rules:
+  - name: owned
+    required-attrs: [owner]
+    message: "{{.Name}} has no owner"
+`))
+	if err != nil {
+		panic(err)
+	}
+
+	vs, err := clutter.Lint(context.Background(), cfg, idx, clutter.LintOptions{})
+	if err != nil {
+		panic(err)
+	}
+
+	for _, v := range vs {
+		fmt.Println(v.Rule, v.Severity, v.Entry.Loc, v.Message)
+	}
+
+	// Output:
+	// owned error auth.go:9.4-21 auth-* has no owner
+	// owned error auth.go:6.4-18 auth-flow has no owner
+}
@@ -0,0 +1,115 @@
+package clutter
+
+import (
+	"github.com/cluttercode/clutter/internal/pkg/federation"
+	"github.com/cluttercode/clutter/internal/pkg/index"
+)
+
+// Index is a sorted set of entries, by name and then location. It is not
+// modified by its methods, and is safe for concurrent use.
+type Index struct {
+	idx *index.Index
+}
+
+func NewIndex(ents []*Entry) *Index {
+	ients := make([]*index.Entry, len(ents))
+	for i, ent := range ents {
+		ients[i] = ent.internal()
+	}
+
+	return &Index{idx: index.NewIndex(ients)}
+}
+
+// ReadIndex reads an index file, as written by clutter index or WriteIndex.
+// The path - reads from stdin.
+func ReadIndex(path string) (*Index, error) {
+	if path == "" {
+		path = "-"
+	}
+
+	idx, err := index.ReadFile(path)
+	if err != nil {
+		return nil, &IndexError{Path: path, Err: err}
+	}
+
+	return &Index{idx: idx}, nil
+}
+
+// WriteIndex writes idx to the file at path, replacing it atomically. The
+// path - writes to stdout.
+func WriteIndex(path string, idx *Index) error {
+	if err := index.WriteFile(path, idx.idx, "api"); err != nil {
+		return &IndexError{Path: path, Err: err}
+	}
+
+	return nil
+}
+
+func (i *Index) Size() int { return i.idx.Size() }
+
+// Entries returns copies of all entries, in order.
+func (i *Index) Entries() []*Entry { return newEntries(i.idx.Slice()) }
+
+// Merge returns an index of the entries of all idxs.
+func Merge(idxs ...*Index) *Index {
+	merged := index.NewIndex(nil)
+
+	for _, idx := range idxs {
+		merged.Add(idx.idx.Slice())
+	}
+
+	return &Index{idx: merged}
+}
+
+// MergeRepo returns an index of the entries of i and of other, the index of
+// another repository, as search and resolve do for repos configured in the
+// clutter configuration file: paths and scopes of other's entries are
+// prefixed by prefix, or @name/ if it is empty, and they get a repo attribute
+// with the repository's name.
+func (i *Index) MergeRepo(name, prefix string, other *Index) *Index {
+	r := federation.Repo{Name: name, Prefix: prefix}
+
+	return &Index{idx: Merge(i).idx.Add(r.Entries(other.idx.Slice()))}
+}
+
+// Search returns the entries matching m, in order.
+func (i *Index) Search(m *Matcher) []*Entry {
+	var ents []*Entry
+
+	_ = index.ForEach(i.idx, func(ent *index.Entry) error {
+		if m.m(ent) {
+			ents = append(ents, newEntry(ent))
+		}
+
+		return nil
+	})
+
+	return ents
+}
+
+// At returns the entry at loc, or ErrNoTag if there is none.
+func (i *Index) At(loc Loc) (*Entry, error) {
+	ent := i.at(loc)
+	if ent == nil {
+		return nil, ErrNoTag
+	}
+
+	return newEntry(ent), nil
+}
+
+func (i *Index) at(loc Loc) *index.Entry {
+	iloc := loc.internal()
+
+	var found *index.Entry
+
+	_ = index.ForEach(i.idx, func(ent *index.Entry) error {
+		if ent.Loc.Contains(iloc) {
+			found = ent
+			return index.ErrStop
+		}
+
+		return nil
+	})
+
+	return found
+}
@@ -0,0 +1,126 @@
+package clutter
+
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/linter"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+// LintConfig is a set of lint rules.
+type LintConfig struct {
+	cfg linter.Config
+}
+
+// ParseLintConfig parses lint rules in the YAML format of the linter section
+// of the clutter configuration file, for example:
+//
+//	rules:
+//	  - name: owned
+//	    required-attrs: [owner]
+func ParseLintConfig(bs []byte) (*LintConfig, error) {
+	var cfg linter.Config
+
+	if err := yaml.UnmarshalStrict(bs, &cfg); err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+
+	if _, err := linter.NewLinter(zlog.NewNopLogger(), cfg); err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+
+	return &LintConfig{cfg: cfg}, nil
+}
+
+type LintOptions struct {
+	Jobs int // rules evaluated concurrently. 0 means number of CPUs.
+
+	Logger *zlog.Logger // optional.
+}
+
+// Violation is a violation of a lint rule by an entry.
+type Violation struct {
+	Entry       *Entry
+	Rule        string // the rule's name, or its number if it has none.
+	Severity    string // error, warning or info.
+	Description string // of the rule.
+	Message     string // the rule's message for the entry, if it has one.
+	TimedOut    bool   // the rule timed out rather than failed.
+}
+
+// Lint checks all entries in idx against the rules in cfg, as clutter lint
+// does, and returns the violations that are not suppressed by pragmas, in the
+// order of idx and then of the rules.
+func Lint(ctx context.Context, cfg *LintConfig, idx *Index, opts LintOptions) ([]*Violation, error) {
+	l, err := linter.NewLinter(logger(opts.Logger), cfg.cfg)
+	if err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+
+	indexFails, err := l.LintIndex(ctx, idx.idx)
+	if err != nil {
+		return nil, fmt.Errorf("lint index: %w", err)
+	}
+
+	ents := idx.idx.Slice()
+
+	results, err := l.LintEntries(ctx, ents, opts.Jobs)
+	if err != nil {
+		return nil, err
+	}
+
+	sups := l.NewSuppressions()
+
+	var vs []*Violation
+
+	for i, ent := range ents {
+		timedOut := make(map[int]bool, len(results[i].TimedOut))
+		for _, ri := range results[i].TimedOut {
+			timedOut[ri] = true
+		}
+
+		fails := sups.Filter(ent, linter.Union(results[i].Fails, indexFails[ent], results[i].TimedOut))
+
+		for _, ri := range fails {
+			v, err := newViolation(l, ri, ent, timedOut[ri])
+			if err != nil {
+				return nil, err
+			}
+
+			vs = append(vs, v)
+		}
+	}
+
+	return vs, nil
+}
+
+func newViolation(l *linter.Linter, ri int, ent *index.Entry, timedOut bool) (*Violation, error) {
+	rule := l.Rule(ri)
+
+	v := &Violation{
+		Entry:       newEntry(ent),
+		Rule:        l.RuleName(ri),
+		Severity:    string(rule.Severity),
+		Description: rule.Description,
+		TimedOut:    timedOut,
+	}
+
+	if timedOut {
+		v.Message = fmt.Sprintf("timed out after %v", rule.Timeout)
+		return v, nil
+	}
+
+	msg, err := l.Message(ri, ent)
+	if err != nil {
+		return nil, fmt.Errorf("rule %s: message: %w", v.Rule, err)
+	}
+
+	v.Message = msg
+
+	return v, nil
+}
@@ -0,0 +1,77 @@
+package clutter
+
+import (
+	"fmt"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+)
+
+// MatchKind is the kind of patterns of a matcher, as in search tags.
+type MatchKind string
+
+const (
+	MatchExact  MatchKind = "exact"
+	MatchGlob   MatchKind = "glob"
+	MatchRegexp MatchKind = "regexp"
+)
+
+// Matcher matches entries, as clutter search does. Search tags are never
+// matched.
+type Matcher struct {
+	m func(*index.Entry) bool
+}
+
+// NewMatcher returns a matcher of entries whose names match name, unless it
+// is empty, and that have all attrs with values matching their patterns.
+// Patterns for the attribute loc match entries' locations.
+func NewMatcher(kind MatchKind, name string, attrs map[string]string) (*Matcher, error) {
+	switch kind {
+	case MatchExact, MatchGlob, MatchRegexp:
+	default:
+		return nil, &ConfigError{Err: fmt.Errorf("invalid match kind %q", kind)}
+	}
+
+	ent := index.Entry{Name: name, Attrs: index.Attrs{"search": string(kind)}}
+
+	for k, v := range attrs {
+		if k == "search" {
+			return nil, &ConfigError{Err: fmt.Errorf("search is not a valid attribute pattern")}
+		}
+
+		ent.Attrs[k] = v
+	}
+
+	m, err := ent.Matcher()
+	if err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+
+	return &Matcher{m: m}, nil
+}
+
+// CompileQuery returns a matcher of entries matching a query expression, such
+// as: name~^api- and (lang=go or lang=py) and not deprecated.
+func CompileQuery(query string) (*Matcher, error) {
+	m, err := index.CompileQuery(query)
+	if err != nil {
+		return nil, &ConfigError{Err: fmt.Errorf("query: %w", err)}
+	}
+
+	return &Matcher{m: m}, nil
+}
+
+// SearchMatcher returns the matcher of a search tag.
+func SearchMatcher(ent *Entry) (*Matcher, error) {
+	if _, ok := ent.IsSearch(); !ok {
+		return nil, &ConfigError{Err: fmt.Errorf("not a search tag")}
+	}
+
+	m, err := ent.internal().Matcher()
+	if err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+
+	return &Matcher{m: m}, nil
+}
+
+func (m *Matcher) Match(ent *Entry) bool { return m.m(ent.internal()) }
@@ -0,0 +1,34 @@
+package clutter
+
+import (
+	"github.com/cluttercode/clutter/internal/pkg/parser"
+)
+
+// ParseElement parses the text of elem into an entry, applying syntactic
+// sugar and pragmas. Errors are ParseErrors.
+func ParseElement(elem *Element) (*Entry, error) {
+	ent, err := parser.ParseElement(elem.internal())
+	if err != nil {
+		return nil, &ParseError{Text: elem.Text, Loc: elem.Loc, Err: err}
+	}
+
+	return newEntry(ent), nil
+}
+
+// ParseElements parses all elems, stopping at the first error.
+func ParseElements(elems []*Element) ([]*Entry, error) {
+	ents := make([]*Entry, len(elems))
+
+	for i, elem := range elems {
+		var err error
+		if ents[i], err = ParseElement(elem); err != nil {
+			return nil, err
+		}
+	}
+
+	return ents, nil
+}
+
+// FormatEntry returns the text of a tag, without brackets, that parses back
+// into ent.
+func FormatEntry(ent *Entry) string { return parser.FormatElement(ent.internal()) }
@@ -0,0 +1,57 @@
+package clutter
+
+import (
+	"fmt"
+
+	"github.com/cluttercode/clutter/internal/pkg/index"
+	"github.com/cluttercode/clutter/internal/pkg/resolver"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+// ResolveOptions selects what Resolve returns. By default, it returns all
+// the matches. Next and Prev are mutually exclusive, and are ignored for
+// search tags.
+type ResolveOptions struct {
+	Next   bool // only the match after the tag.
+	Prev   bool // only the match before the tag.
+	Cyclic bool // Next and Prev wrap around.
+
+	Logger *zlog.Logger // optional.
+}
+
+// Resolve returns the entries in idx that the tag at loc refers to, as
+// clutter resolve does: tags with the same name in its scope, or the matches
+// of a search tag. It returns ErrNoTag if idx has no tag at loc.
+func Resolve(idx *Index, loc Loc, opts ResolveOptions) ([]*Entry, error) {
+	if opts.Next && opts.Prev {
+		return nil, &ConfigError{Err: fmt.Errorf("next and prev are mutually exclusive")}
+	}
+
+	what := idx.at(loc)
+	if what == nil {
+		return nil, ErrNoTag
+	}
+
+	z := logger(opts.Logger).With("what", what)
+
+	var (
+		ents []*index.Entry
+		err  error
+	)
+
+	switch {
+	case opts.Next:
+		ents, err = resolver.ResolveNext(z, what, idx.idx, opts.Cyclic)
+	case opts.Prev:
+		ents, err = resolver.ResolvePrev(z, what, idx.idx, opts.Cyclic)
+	default:
+		ents, err = resolver.ResolveList(z, what, idx.idx)
+	}
+
+	if err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+
+	return newEntries(index.NewIndex(ents).Slice()), nil
+}
@@ -0,0 +1,124 @@
+package clutter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cluttercode/clutter/internal/pkg/scanner"
+
+	"github.com/cluttercode/clutter/pkg/zlog"
+)
+
+// ScanConfig configures scanning, as the scanner section of the clutter
+// configuration file does. The zero value scans all files but .git for tags
+// in the default brackets.
+type ScanConfig struct {
+	Left, Right  string   // brackets. Default: the clutter command's.
+	Ignore       []string // .gitignore formatted patterns of paths to ignore. Default: .git.
+	CommentsOnly bool     // scan tags only in comments, by file language.
+	GitIgnore    bool     // also ignore paths ignored by git.
+	GitTracked   bool     // scan only files tracked by git.
+	Workers      int      // files scanned concurrently. 0 means number of CPUs.
+
+	Logger *zlog.Logger // optional.
+}
+
+func (c *ScanConfig) internal() (scanner.Config, error) {
+	cfg := scanner.Config{
+		Bracket:   scanner.BracketConfig{Left: c.Left, Right: c.Right},
+		Ignore:    c.Ignore,
+		Mode:      scanner.ModeRaw,
+		Workers:   c.Workers,
+		GitIgnore: c.GitIgnore,
+		Source:    scanner.SourceWalk,
+	}
+
+	cfg.Bracket.OverrideWith(scanner.BracketConfig{
+		Left:  "[#",
+		Right: "#]",
+	})
+
+	if cfg.Bracket.Left == "" || cfg.Bracket.Right == "" {
+		return cfg, &ConfigError{Err: fmt.Errorf("both brackets must be specified")}
+	}
+
+	if c.CommentsOnly {
+		cfg.Mode = scanner.ModeComments
+	}
+
+	if c.GitTracked {
+		cfg.Source = scanner.SourceGit
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, &ConfigError{Err: err}
+	}
+
+	return cfg, nil
+}
+
+func logger(z *zlog.Logger) *zlog.Logger {
+	if z == nil {
+		return zlog.NewNopLogger()
+	}
+
+	return z
+}
+
+// ScanTree scans all files under root. Files that fail to scan are reported
+// as ScanErrors, along with the elements of all other files.
+func ScanTree(cfg ScanConfig, root string) ([]*Element, error) {
+	scfg, err := cfg.internal()
+	if err != nil {
+		return nil, err
+	}
+
+	scan, err := scanner.NewScanner(logger(cfg.Logger), scfg)
+	if err != nil {
+		return nil, &ConfigError{Err: err}
+	}
+
+	raws, err := scan(root, nil)
+
+	var ferrs scanner.FileErrors
+	if err != nil && !errors.As(err, &ferrs) {
+		return nil, err
+	}
+
+	elems := make([]*Element, len(raws))
+	for i, raw := range raws {
+		elems[i] = newElement(raw)
+	}
+
+	if len(ferrs) > 0 {
+		errs := make(ScanErrors, len(ferrs))
+		for i, ferr := range ferrs {
+			errs[i] = &ScanError{Path: ferr.Path, Err: ferr.Err}
+		}
+
+		return elems, errs
+	}
+
+	return elems, nil
+}
+
+// ScanReader scans r as if it is the content of the file at path, which
+// determines its language if scanning only comments.
+func ScanReader(cfg ScanConfig, path string, r io.Reader) ([]*Element, error) {
+	scfg, err := cfg.internal()
+	if err != nil {
+		return nil, err
+	}
+
+	var elems []*Element
+
+	if err := scanner.ScanReader(logger(cfg.Logger), scfg, path, r, func(raw *scanner.RawElement) error {
+		elems = append(elems, newElement(raw))
+		return nil
+	}); err != nil {
+		return nil, &ScanError{Path: path, Err: err}
+	}
+
+	return elems, nil
+}
@@ -0,0 +1,144 @@
+// Adapted from https://github.com/go-git/go-git.
+
+package gitignore
+
+import (
+	"bufio"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	commentPrefix   = "#"
+	coreSection     = "core"
+	excludesFileKey = "excludesfile"
+	gitDir          = ".git"
+	gitignoreFile   = ".gitignore"
+	infoExcludeFile = gitDir + "/info/exclude"
+)
+
+// ReadIgnoreFile reads a specific git ignore file. Patterns are scoped to
+// domain. A missing file is not an error and yields no patterns.
+func ReadIgnoreFile(path string, domain []string) (ps []Pattern, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		s := scanner.Text()
+		if !strings.HasPrefix(s, commentPrefix) && len(strings.TrimSpace(s)) > 0 {
+			ps = append(ps, ParsePattern(s, domain))
+		}
+	}
+
+	return ps, scanner.Err()
+}
+
+// ReadDirPatterns reads the .gitignore file in the directory at root/path,
+// scoping its patterns to path. It does not descend into subdirectories.
+func ReadDirPatterns(root string, path []string) ([]Pattern, error) {
+	return ReadIgnoreFile(filepath.Join(append(append([]string{root}, path...), gitignoreFile)...), path)
+}
+
+// ReadRepoPatterns reads .git/info/exclude of the repository at root.
+func ReadRepoPatterns(root string) ([]Pattern, error) {
+	return ReadIgnoreFile(filepath.Join(root, filepath.FromSlash(infoExcludeFile)), nil)
+}
+
+// LoadGlobalPatterns loads patterns from the global excludes file, as
+// specified by core.excludesFile in ~/.gitconfig, or from its default
+// location $XDG_CONFIG_HOME/git/ignore.
+func LoadGlobalPatterns() ([]Pattern, error) {
+	home, err := homeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := readExcludesFile(filepath.Join(home, ".gitconfig"))
+	if err != nil {
+		return nil, err
+	}
+
+	if path == "" {
+		xdg := os.Getenv("XDG_CONFIG_HOME")
+		if xdg == "" {
+			xdg = filepath.Join(home, ".config")
+		}
+
+		path = filepath.Join(xdg, "git", "ignore")
+	} else if strings.HasPrefix(path, "~/") {
+		path = filepath.Join(home, path[2:])
+	}
+
+	return ReadIgnoreFile(path, nil)
+}
+
+func homeDir() (string, error) {
+	if home := os.Getenv("HOME"); home != "" {
+		return home, nil
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+
+	return u.HomeDir, nil
+}
+
+// readExcludesFile extracts core.excludesFile from a git config file. Only the
+// simple form of the config syntax is supported.
+func readExcludesFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	defer f.Close()
+
+	var (
+		section string
+		value   string
+	)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		s := strings.TrimSpace(scanner.Text())
+
+		if s == "" || s[0] == '#' || s[0] == ';' {
+			continue
+		}
+
+		if s[0] == '[' {
+			section = strings.ToLower(strings.Trim(s, "[] \t"))
+			continue
+		}
+
+		if section != coreSection {
+			continue
+		}
+
+		parts := strings.SplitN(s, "=", 2)
+		if len(parts) != 2 || strings.ToLower(strings.TrimSpace(parts[0])) != excludesFileKey {
+			continue
+		}
+
+		value = strings.Trim(strings.TrimSpace(parts[1]), `"`)
+	}
+
+	return value, scanner.Err()
+}
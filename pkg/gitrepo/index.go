@@ -0,0 +1,144 @@
+package gitrepo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// IndexEntry is a single file entry in the index (staging area).
+type IndexEntry struct {
+	Path string // slash separated.
+	Mode uint32
+	Hash Hash
+}
+
+// ReadIndex reads the repository index file, which lists all tracked files.
+// Conflicted paths, which have multiple entries in different stages, are
+// returned once.
+func (r *Repo) ReadIndex() ([]IndexEntry, error) {
+	bs, err := ioutil.ReadFile(filepath.Join(r.gitDir, "index"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // empty repo.
+		}
+
+		return nil, err
+	}
+
+	return parseIndex(bs)
+}
+
+func parseIndex(bs []byte) ([]IndexEntry, error) {
+	if len(bs) < 12 || !bytes.Equal(bs[:4], []byte("DIRC")) {
+		return nil, fmt.Errorf("invalid index signature")
+	}
+
+	version := binary.BigEndian.Uint32(bs[4:8])
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported index version %d", version)
+	}
+
+	n := int(binary.BigEndian.Uint32(bs[8:12]))
+
+	const (
+		fixedLen     = 62 // stat data (40) + hash (20) + flags (2).
+		flagExtended = 0x4000
+		flagStage    = 0x3000
+	)
+
+	var (
+		ents = make([]IndexEntry, 0, n)
+		prev []byte
+		at   = 12
+		errT = fmt.Errorf("truncated index")
+	)
+
+	for i := 0; i < n; i++ {
+		start := at
+
+		if len(bs) < at+fixedLen {
+			return nil, errT
+		}
+
+		ent := IndexEntry{Mode: binary.BigEndian.Uint32(bs[at+24:])}
+		copy(ent.Hash[:], bs[at+40:at+60])
+
+		flags := binary.BigEndian.Uint16(bs[at+60:])
+
+		at += fixedLen
+
+		if version >= 3 && flags&flagExtended != 0 {
+			at += 2
+		}
+
+		var name []byte
+
+		if version == 4 {
+			// path is prefix compressed relative to the previous entry.
+			strip, w := offsetVarint(bs[at:])
+			if w == 0 || int(strip) > len(prev) {
+				return nil, errT
+			}
+
+			at += w
+
+			nul := bytes.IndexByte(bs[at:], 0)
+			if nul < 0 {
+				return nil, errT
+			}
+
+			name = append(append([]byte{}, prev[:len(prev)-int(strip)]...), bs[at:at+nul]...)
+
+			at += nul + 1
+		} else {
+			nul := bytes.IndexByte(bs[at:], 0)
+			if nul < 0 {
+				return nil, errT
+			}
+
+			name = bs[at : at+nul]
+
+			// entries are padded with 1-8 nuls to a multiple of 8 bytes.
+			at = start + ((at+nul-start)/8+1)*8
+		}
+
+		prev = name
+
+		if ent.Path = string(name); flags&flagStage != 0 && len(ents) > 0 && ents[len(ents)-1].Path == ent.Path {
+			continue
+		}
+
+		ents = append(ents, ent)
+	}
+
+	return ents, nil
+}
+
+// offsetVarint decodes the variable length integer encoding used by git for
+// offsets, returning the value and number of bytes read (0 on error).
+func offsetVarint(bs []byte) (uint64, int) {
+	if len(bs) == 0 {
+		return 0, 0
+	}
+
+	b := bs[0]
+	n := uint64(b & 0x7f)
+
+	i := 1
+	for b&0x80 != 0 {
+		if i >= len(bs) {
+			return 0, 0
+		}
+
+		b = bs[i]
+		i++
+
+		n = ((n + 1) << 7) | uint64(b&0x7f)
+	}
+
+	return n, i
+}
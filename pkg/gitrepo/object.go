@@ -0,0 +1,286 @@
+package gitrepo
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type ObjectType string
+
+const (
+	TypeCommit ObjectType = "commit"
+	TypeTree   ObjectType = "tree"
+	TypeBlob   ObjectType = "blob"
+	TypeTag    ObjectType = "tag"
+)
+
+// HashObject returns the name of an object of type typ with content data, as
+// git hash-object does. The object need not exist in any repository.
+func HashObject(typ ObjectType, data []byte) Hash {
+	h := sha1.New()
+
+	fmt.Fprintf(h, "%s %d\x00", typ, len(data))
+	h.Write(data)
+
+	var hash Hash
+	copy(hash[:], h.Sum(nil))
+
+	return hash
+}
+
+// ReadObject returns the type and content of the object named h.
+func (r *Repo) ReadObject(h Hash) (ObjectType, []byte, error) {
+	typ, data, err := r.readLoose(h)
+	if err == nil || !os.IsNotExist(err) {
+		return typ, data, err
+	}
+
+	packs, err := r.loadPacks()
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, p := range packs {
+		if off, ok := p.idx.find(h); ok {
+			return p.readAt(r, off)
+		}
+	}
+
+	return "", nil, fmt.Errorf("object %v: %w", h, ErrNotFound)
+}
+
+func (r *Repo) readLoose(h Hash) (ObjectType, []byte, error) {
+	s := h.String()
+
+	f, err := os.Open(filepath.Join(r.commonDir, "objects", s[:2], s[2:]))
+	if err != nil {
+		return "", nil, err // do not wrap, checked by IsNotExist.
+	}
+
+	defer f.Close()
+
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return "", nil, fmt.Errorf("object %v: %w", h, err)
+	}
+
+	defer zr.Close()
+
+	bs, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return "", nil, fmt.Errorf("object %v: %w", h, err)
+	}
+
+	nul := bytes.IndexByte(bs, 0)
+	if nul < 0 {
+		return "", nil, fmt.Errorf("object %v: invalid header", h)
+	}
+
+	hdr := strings.SplitN(string(bs[:nul]), " ", 2)
+	if len(hdr) != 2 {
+		return "", nil, fmt.Errorf("object %v: invalid header", h)
+	}
+
+	if n, err := strconv.Atoi(hdr[1]); err != nil || n != len(bs)-nul-1 {
+		return "", nil, fmt.Errorf("object %v: invalid size", h)
+	}
+
+	return ObjectType(hdr[0]), bs[nul+1:], nil
+}
+
+func (r *Repo) readTyped(h Hash, exp ObjectType) ([]byte, error) {
+	typ, data, err := r.ReadObject(h)
+	if err != nil {
+		return nil, err
+	}
+
+	if typ != exp {
+		return nil, fmt.Errorf("%v is a %s, expected %s", h, typ, exp)
+	}
+
+	return data, nil
+}
+
+// ReadBlob returns the content of the blob named h.
+func (r *Repo) ReadBlob(h Hash) ([]byte, error) { return r.readTyped(h, TypeBlob) }
+
+type Commit struct {
+	Tree    Hash
+	Parents []Hash
+}
+
+func (r *Repo) ReadCommit(h Hash) (*Commit, error) {
+	data, err := r.readTyped(h, TypeCommit)
+	if err != nil {
+		return nil, err
+	}
+
+	var c Commit
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break
+		}
+
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		switch parts[0] {
+		case "tree":
+			if c.Tree, err = ParseHash(parts[1]); err != nil {
+				return nil, fmt.Errorf("commit %v: tree: %w", h, err)
+			}
+		case "parent":
+			p, err := ParseHash(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("commit %v: parent: %w", h, err)
+			}
+
+			c.Parents = append(c.Parents, p)
+		}
+	}
+
+	if c.Tree.IsZero() {
+		return nil, fmt.Errorf("commit %v: missing tree", h)
+	}
+
+	return &c, nil
+}
+
+const (
+	ModeDir     = 0040000
+	ModeFile    = 0100644
+	ModeExec    = 0100755
+	ModeSymlink = 0120000
+	ModeGitlink = 0160000
+)
+
+type TreeEntry struct {
+	Name string
+	Mode uint32
+	Hash Hash
+}
+
+func (r *Repo) ReadTree(h Hash) ([]TreeEntry, error) {
+	data, err := r.readTyped(h, TypeTree)
+	if err != nil {
+		return nil, err
+	}
+
+	var ents []TreeEntry
+
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+
+		if sp < 0 || nul < sp || len(data) < nul+21 {
+			return nil, fmt.Errorf("tree %v: invalid entry", h)
+		}
+
+		mode, err := strconv.ParseUint(string(data[:sp]), 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("tree %v: invalid mode: %w", h, err)
+		}
+
+		ent := TreeEntry{Name: string(data[sp+1 : nul]), Mode: uint32(mode)}
+		copy(ent.Hash[:], data[nul+1:nul+21])
+
+		ents = append(ents, ent)
+
+		data = data[nul+21:]
+	}
+
+	return ents, nil
+}
+
+// WalkTree calls f for every file (blob) under the tree of the commit h,
+// recursively. Paths are slash separated and relative to the tree root.
+func (r *Repo) WalkTree(h Hash, f func(path string, mode uint32, blob Hash) error) error {
+	c, err := r.ReadCommit(h)
+	if err != nil {
+		return err
+	}
+
+	return r.walkTree(c.Tree, "", f)
+}
+
+func (r *Repo) walkTree(h Hash, prefix string, f func(string, uint32, Hash) error) error {
+	ents, err := r.ReadTree(h)
+	if err != nil {
+		return err
+	}
+
+	for _, ent := range ents {
+		path := prefix + ent.Name
+
+		switch ent.Mode {
+		case ModeDir:
+			if err := r.walkTree(ent.Hash, path+"/", f); err != nil {
+				return err
+			}
+		case ModeGitlink:
+			// submodule - commit is not in this repo.
+		default:
+			if err := f(path, ent.Mode, ent.Hash); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// FindBlob returns the blob at path in the tree of commit h.
+func (r *Repo) FindBlob(h Hash, path string) (Hash, error) {
+	c, err := r.ReadCommit(h)
+	if err != nil {
+		return Hash{}, err
+	}
+
+	tree := c.Tree
+
+	parts := strings.Split(path, "/")
+
+E:
+	for i, part := range parts {
+		ents, err := r.ReadTree(tree)
+		if err != nil {
+			return Hash{}, err
+		}
+
+		for _, ent := range ents {
+			if ent.Name != part {
+				continue
+			}
+
+			if i == len(parts)-1 {
+				if ent.Mode == ModeDir || ent.Mode == ModeGitlink {
+					return Hash{}, fmt.Errorf("%s: not a file", path)
+				}
+
+				return ent.Hash, nil
+			}
+
+			if ent.Mode != ModeDir {
+				return Hash{}, fmt.Errorf("%s: not a directory", strings.Join(parts[:i+1], "/"))
+			}
+
+			tree = ent.Hash
+
+			continue E
+		}
+
+		break
+	}
+
+	return Hash{}, fmt.Errorf("%s: %w", path, ErrNotFound)
+}
@@ -0,0 +1,331 @@
+package gitrepo
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	packObjCommit   = 1
+	packObjTree     = 2
+	packObjBlob     = 3
+	packObjTag      = 4
+	packObjOfsDelta = 6
+	packObjRefDelta = 7
+)
+
+var packTypes = map[byte]ObjectType{
+	packObjCommit: TypeCommit,
+	packObjTree:   TypeTree,
+	packObjBlob:   TypeBlob,
+	packObjTag:    TypeTag,
+}
+
+type packIdx struct {
+	hashes  []Hash // sorted.
+	offsets []int64
+}
+
+func (idx *packIdx) find(h Hash) (int64, bool) {
+	i := sort.Search(len(idx.hashes), func(i int) bool {
+		return bytes.Compare(idx.hashes[i][:], h[:]) >= 0
+	})
+
+	if i < len(idx.hashes) && idx.hashes[i] == h {
+		return idx.offsets[i], true
+	}
+
+	return 0, false
+}
+
+type pack struct {
+	path string
+	idx  *packIdx
+}
+
+func (r *Repo) loadPacks() ([]*pack, error) {
+	r.packsOnce.Do(func() {
+		dir := filepath.Join(r.commonDir, "objects", "pack")
+
+		fis, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				r.packsErr = err
+			}
+
+			return
+		}
+
+		for _, fi := range fis {
+			name := fi.Name()
+			if !strings.HasSuffix(name, ".idx") {
+				continue
+			}
+
+			idx, err := readPackIdx(filepath.Join(dir, name))
+			if err != nil {
+				r.packsErr = fmt.Errorf("%s: %w", name, err)
+				return
+			}
+
+			r.packs = append(r.packs, &pack{
+				path: filepath.Join(dir, strings.TrimSuffix(name, ".idx")+".pack"),
+				idx:  idx,
+			})
+		}
+	})
+
+	return r.packs, r.packsErr
+}
+
+func readPackIdx(path string) (*packIdx, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bs) < 8+256*4 || !bytes.Equal(bs[:4], []byte{0xff, 't', 'O', 'c'}) {
+		return nil, fmt.Errorf("unsupported pack index version")
+	}
+
+	if v := binary.BigEndian.Uint32(bs[4:8]); v != 2 {
+		return nil, fmt.Errorf("unsupported pack index version %d", v)
+	}
+
+	n := int(binary.BigEndian.Uint32(bs[8+255*4:]))
+
+	var (
+		hashesAt  = 8 + 256*4
+		offsetsAt = hashesAt + n*20 + n*4 // skip crcs.
+		largeAt   = offsetsAt + n*4
+	)
+
+	if len(bs) < largeAt {
+		return nil, fmt.Errorf("truncated pack index")
+	}
+
+	idx := &packIdx{hashes: make([]Hash, n), offsets: make([]int64, n)}
+
+	for i := 0; i < n; i++ {
+		copy(idx.hashes[i][:], bs[hashesAt+i*20:])
+
+		off := binary.BigEndian.Uint32(bs[offsetsAt+i*4:])
+		if off&0x80000000 == 0 {
+			idx.offsets[i] = int64(off)
+			continue
+		}
+
+		at := largeAt + int(off&0x7fffffff)*8
+		if len(bs) < at+8 {
+			return nil, fmt.Errorf("truncated pack index")
+		}
+
+		idx.offsets[i] = int64(binary.BigEndian.Uint64(bs[at:]))
+	}
+
+	return idx, nil
+}
+
+func (p *pack) readAt(r *Repo, off int64) (ObjectType, []byte, error) {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer f.Close()
+
+	return p.readObject(r, f, off, 0)
+}
+
+func (p *pack) readObject(r *Repo, f *os.File, off int64, depth int) (ObjectType, []byte, error) {
+	if depth > 50 {
+		return "", nil, fmt.Errorf("delta chain too long")
+	}
+
+	br := bufio.NewReader(io.NewSectionReader(f, off, 1<<62))
+
+	b, err := br.ReadByte()
+	if err != nil {
+		return "", nil, err
+	}
+
+	typ := (b >> 4) & 7
+	size := uint64(b & 0x0f)
+
+	for shift := uint(4); b&0x80 != 0; shift += 7 {
+		if b, err = br.ReadByte(); err != nil {
+			return "", nil, err
+		}
+
+		size |= uint64(b&0x7f) << shift
+	}
+
+	var (
+		baseType ObjectType
+		base     []byte
+	)
+
+	switch typ {
+	case packObjOfsDelta:
+		b, err := br.ReadByte()
+		if err != nil {
+			return "", nil, err
+		}
+
+		rel := int64(b & 0x7f)
+
+		for b&0x80 != 0 {
+			if b, err = br.ReadByte(); err != nil {
+				return "", nil, err
+			}
+
+			rel = ((rel + 1) << 7) | int64(b&0x7f)
+		}
+
+		if baseType, base, err = p.readObject(r, f, off-rel, depth+1); err != nil {
+			return "", nil, fmt.Errorf("delta base: %w", err)
+		}
+
+	case packObjRefDelta:
+		var h Hash
+		if _, err := io.ReadFull(br, h[:]); err != nil {
+			return "", nil, err
+		}
+
+		if baseType, base, err = r.ReadObject(h); err != nil {
+			return "", nil, fmt.Errorf("delta base: %w", err)
+		}
+	}
+
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return "", nil, err
+	}
+
+	defer zr.Close()
+
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if uint64(len(data)) != size {
+		return "", nil, fmt.Errorf("object size mismatch")
+	}
+
+	if base == nil {
+		t, ok := packTypes[typ]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown packed object type %d", typ)
+		}
+
+		return t, data, nil
+	}
+
+	if data, err = applyDelta(base, data); err != nil {
+		return "", nil, err
+	}
+
+	return baseType, data, nil
+}
+
+func applyDelta(base, delta []byte) ([]byte, error) {
+	errInvalid := fmt.Errorf("invalid delta")
+
+	varint := func() (uint64, bool) {
+		var (
+			n     uint64
+			shift uint
+		)
+
+		for len(delta) > 0 {
+			b := delta[0]
+			delta = delta[1:]
+
+			n |= uint64(b&0x7f) << shift
+			shift += 7
+
+			if b&0x80 == 0 {
+				return n, true
+			}
+		}
+
+		return 0, false
+	}
+
+	srcSize, ok := varint()
+	if !ok || srcSize != uint64(len(base)) {
+		return nil, errInvalid
+	}
+
+	dstSize, ok := varint()
+	if !ok {
+		return nil, errInvalid
+	}
+
+	out := make([]byte, 0, dstSize)
+
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+
+		if op&0x80 == 0 {
+			// insert.
+			n := int(op)
+			if n == 0 || n > len(delta) {
+				return nil, errInvalid
+			}
+
+			out = append(out, delta[:n]...)
+			delta = delta[n:]
+
+			continue
+		}
+
+		// copy from base.
+		var off, n uint64
+
+		for i := uint(0); i < 7; i++ {
+			if op&(1<<i) == 0 {
+				continue
+			}
+
+			if len(delta) == 0 {
+				return nil, errInvalid
+			}
+
+			if i < 4 {
+				off |= uint64(delta[0]) << (8 * i)
+			} else {
+				n |= uint64(delta[0]) << (8 * (i - 4))
+			}
+
+			delta = delta[1:]
+		}
+
+		if n == 0 {
+			n = 0x10000
+		}
+
+		if off+n > uint64(len(base)) {
+			return nil, errInvalid
+		}
+
+		out = append(out, base[off:off+n]...)
+	}
+
+	if uint64(len(out)) != dstSize {
+		return nil, errInvalid
+	}
+
+	return out, nil
+}
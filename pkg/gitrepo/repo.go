@@ -0,0 +1,351 @@
+// Package gitrepo implements read only access to a local git repository: its
+// refs, objects (loose and packed), trees and index file. It does not require
+// the git binary, nor any network access.
+package gitrepo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Hash is a SHA-1 object name.
+type Hash [20]byte
+
+func (h Hash) String() string { return hex.EncodeToString(h[:]) }
+
+func (h Hash) IsZero() bool { return h == Hash{} }
+
+func ParseHash(s string) (h Hash, err error) {
+	if len(s) != 2*len(h) {
+		return h, fmt.Errorf("invalid hash length")
+	}
+
+	_, err = hex.Decode(h[:], []byte(s))
+
+	return
+}
+
+var ErrNotFound = fmt.Errorf("not found")
+
+type Repo struct {
+	workDir   string // empty for bare repos.
+	gitDir    string
+	commonDir string // differs from gitDir for linked worktrees.
+
+	packsOnce sync.Once
+	packs     []*pack
+	packsErr  error
+}
+
+// Open opens the repository whose working tree (or git directory, for bare
+// repositories) is at path.
+func Open(path string) (*Repo, error) {
+	r := &Repo{workDir: path, gitDir: filepath.Join(path, ".git")}
+
+	fi, err := os.Stat(r.gitDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+
+		// maybe bare.
+		if _, err := os.Stat(filepath.Join(path, "objects")); err != nil {
+			return nil, fmt.Errorf("not a git repository: %s", path)
+		}
+
+		r.workDir, r.gitDir = "", path
+	} else if !fi.IsDir() {
+		// worktrees and submodules have a .git file pointing to the actual dir.
+		bs, err := ioutil.ReadFile(r.gitDir)
+		if err != nil {
+			return nil, err
+		}
+
+		line := strings.TrimSpace(string(bs))
+		if !strings.HasPrefix(line, "gitdir: ") {
+			return nil, fmt.Errorf("invalid .git file")
+		}
+
+		if r.gitDir = strings.TrimPrefix(line, "gitdir: "); !filepath.IsAbs(r.gitDir) {
+			r.gitDir = filepath.Join(path, r.gitDir)
+		}
+	}
+
+	r.commonDir = r.gitDir
+
+	if bs, err := ioutil.ReadFile(filepath.Join(r.gitDir, "commondir")); err == nil {
+		if r.commonDir = strings.TrimSpace(string(bs)); !filepath.IsAbs(r.commonDir) {
+			r.commonDir = filepath.Join(r.gitDir, r.commonDir)
+		}
+	}
+
+	return r, nil
+}
+
+// WorkDir returns the path to the working tree, or an empty string for bare
+// repositories.
+func (r *Repo) WorkDir() string { return r.workDir }
+
+func (r *Repo) readRef(name string, depth int) (Hash, error) {
+	if depth > 5 {
+		return Hash{}, fmt.Errorf("ref %s: too many levels of symbolic refs", name)
+	}
+
+	for _, dir := range []string{r.gitDir, r.commonDir} {
+		bs, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			continue
+		}
+
+		text := strings.TrimSpace(string(bs))
+
+		if strings.HasPrefix(text, "ref: ") {
+			return r.readRef(strings.TrimPrefix(text, "ref: "), depth+1)
+		}
+
+		return ParseHash(text)
+	}
+
+	return r.readPackedRef(name)
+}
+
+func (r *Repo) readPackedRef(name string) (Hash, error) {
+	f, err := os.Open(filepath.Join(r.commonDir, "packed-refs"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Hash{}, ErrNotFound
+		}
+
+		return Hash{}, err
+	}
+
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) == 2 && parts[1] == name {
+			return ParseHash(parts[0])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return Hash{}, err
+	}
+
+	return Hash{}, ErrNotFound
+}
+
+// ResolveRev resolves a revision to a commit hash. Supported are full and
+// abbreviated hashes, HEAD, full ref names, branch, tag and remote names,
+// optionally followed by any number of ~N and ^ suffixes.
+func (r *Repo) ResolveRev(rev string) (Hash, error) {
+	base, suffix := rev, ""
+	if i := strings.IndexAny(rev, "~^"); i > 0 {
+		base, suffix = rev[:i], rev[i:]
+	}
+
+	h, err := r.resolveName(base)
+	if err != nil {
+		return Hash{}, fmt.Errorf("%s: %w", base, err)
+	}
+
+	if h, err = r.PeelToCommit(h); err != nil {
+		return Hash{}, err
+	}
+
+	for suffix != "" {
+		op := suffix[0]
+		suffix = suffix[1:]
+
+		n := 1
+
+		j := 0
+		for j < len(suffix) && suffix[j] >= '0' && suffix[j] <= '9' {
+			j++
+		}
+
+		if j > 0 {
+			n, _ = strconv.Atoi(suffix[:j])
+			suffix = suffix[j:]
+		}
+
+		if op == '^' {
+			// ^N selects the Nth parent.
+			c, err := r.ReadCommit(h)
+			if err != nil {
+				return Hash{}, err
+			}
+
+			if n == 0 {
+				continue
+			}
+
+			if n > len(c.Parents) {
+				return Hash{}, fmt.Errorf("%s: no parent #%d", rev, n)
+			}
+
+			h = c.Parents[n-1]
+
+			continue
+		}
+
+		// ~N selects the Nth first parent ancestor.
+		for ; n > 0; n-- {
+			c, err := r.ReadCommit(h)
+			if err != nil {
+				return Hash{}, err
+			}
+
+			if len(c.Parents) == 0 {
+				return Hash{}, fmt.Errorf("%s: no such ancestor", rev)
+			}
+
+			h = c.Parents[0]
+		}
+	}
+
+	return h, nil
+}
+
+func (r *Repo) resolveName(name string) (Hash, error) {
+	if name == "" || name == "@" {
+		name = "HEAD"
+	}
+
+	patterns := []string{"refs/%s", "refs/tags/%s", "refs/heads/%s", "refs/remotes/%s", "refs/remotes/%s/HEAD"}
+
+	// other files in the git dir, such as config, are not refs.
+	if isRootRef(name) || strings.HasPrefix(name, "refs/") {
+		patterns = append([]string{"%s"}, patterns...)
+	}
+
+	for _, p := range patterns {
+		h, err := r.readRef(fmt.Sprintf(p, name), 0)
+		if err == nil {
+			return h, nil
+		}
+
+		if err != ErrNotFound && !os.IsNotExist(err) {
+			return Hash{}, err
+		}
+	}
+
+	if len(name) >= 4 && len(name) <= 40 {
+		if _, err := hex.DecodeString(name[:len(name)&^1]); err == nil {
+			return r.expandHash(strings.ToLower(name))
+		}
+	}
+
+	return Hash{}, ErrNotFound
+}
+
+// isRootRef returns true if name may be a ref at the root of the git dir,
+// such as HEAD or ORIG_HEAD, which are all upper case.
+func isRootRef(name string) bool {
+	for _, c := range name {
+		if (c < 'A' || c > 'Z') && c != '_' {
+			return false
+		}
+	}
+
+	return name != ""
+}
+
+// expandHash finds the single object whose name starts with prefix.
+func (r *Repo) expandHash(prefix string) (Hash, error) {
+	if len(prefix) == 40 {
+		return ParseHash(prefix)
+	}
+
+	found := map[Hash]bool{}
+
+	dir := filepath.Join(r.commonDir, "objects", prefix[:2])
+	if fis, err := ioutil.ReadDir(dir); err == nil {
+		for _, fi := range fis {
+			if name := prefix[:2] + fi.Name(); strings.HasPrefix(name, prefix) {
+				if h, err := ParseHash(name); err == nil {
+					found[h] = true
+				}
+			}
+		}
+	}
+
+	packs, err := r.loadPacks()
+	if err != nil {
+		return Hash{}, err
+	}
+
+	for _, p := range packs {
+		for _, h := range p.idx.hashes {
+			if strings.HasPrefix(h.String(), prefix) {
+				found[h] = true
+			}
+		}
+	}
+
+	if len(found) > 1 {
+		return Hash{}, fmt.Errorf("ambiguous hash prefix %q", prefix)
+	}
+
+	for h := range found {
+		return h, nil
+	}
+
+	return Hash{}, ErrNotFound
+}
+
+// PeelToCommit dereferences annotated tags until a commit is found.
+func (r *Repo) PeelToCommit(h Hash) (Hash, error) {
+	for i := 0; i < 10; i++ {
+		typ, data, err := r.ReadObject(h)
+		if err != nil {
+			return Hash{}, err
+		}
+
+		switch typ {
+		case TypeCommit:
+			return h, nil
+		case TypeTag:
+			obj, err := headerField(data, "object")
+			if err != nil {
+				return Hash{}, fmt.Errorf("tag %v: %w", h, err)
+			}
+
+			if h, err = ParseHash(obj); err != nil {
+				return Hash{}, fmt.Errorf("tag %v: %w", h, err)
+			}
+		default:
+			return Hash{}, fmt.Errorf("%v is a %s, not a commit", h, typ)
+		}
+	}
+
+	return Hash{}, fmt.Errorf("too many nested tags")
+}
+
+func headerField(data []byte, key string) (string, error) {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			break // end of headers.
+		}
+
+		if bytes.HasPrefix(line, []byte(key+" ")) {
+			return string(line[len(key)+1:]), nil
+		}
+	}
+
+	return "", fmt.Errorf("missing %s", key)
+}
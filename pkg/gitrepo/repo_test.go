@@ -0,0 +1,178 @@
+package gitrepo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func git(t *testing.T, dir string, args ...string) string {
+	return gitStdin(t, dir, "", args...)
+}
+
+func gitStdin(t *testing.T, dir, stdin string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(stdin)
+	// a minimal environment, so user configuration and shell profiles do not
+	// interfere with git.
+	cmd.Env = append(
+		[]string{"PATH=" + os.Getenv("PATH")},
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+dir,
+	)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+func write(t *testing.T, dir, path, text string) {
+	path = filepath.Join(dir, filepath.FromSlash(path))
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "clutter-gitrepo-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	git(t, dir, "init", "-q")
+
+	write(t, dir, "a", strings.Repeat("meow\n", 1000))
+	write(t, dir, "d/b", "woof\n")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-qm", "first")
+	git(t, dir, "tag", "-a", "-m", "v1", "v1")
+
+	// a small change to a large file makes for a delta once packed.
+	write(t, dir, "a", strings.Repeat("meow\n", 1000)+"purr\n")
+	write(t, dir, "d/e/c", "moo\n")
+	git(t, dir, "add", ".")
+	git(t, dir, "commit", "-qm", "second")
+
+	head := git(t, dir, "rev-parse", "HEAD")
+
+	// branches named like files in the git dir.
+	git(t, dir, "branch", "config", "HEAD~1")
+	git(t, dir, "branch", "description")
+
+	check := func(t *testing.T) {
+		r, err := Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for rev, exp := range map[string]string{
+			"HEAD":         head,
+			"master~0":     head,
+			head[:7]:       head,
+			"HEAD~1":       git(t, dir, "rev-parse", "HEAD~1"),
+			"HEAD^":        git(t, dir, "rev-parse", "HEAD~1"),
+			"v1":           git(t, dir, "rev-parse", "v1^{commit}"),
+			"refs/tags/v1": git(t, dir, "rev-parse", "v1^{commit}"),
+			"config":       git(t, dir, "rev-parse", "HEAD~1"),
+			"description":  head,
+		} {
+			if rev == "master~0" {
+				rev = git(t, dir, "rev-parse", "--abbrev-ref", "HEAD") + "~0"
+			}
+
+			h, err := r.ResolveRev(rev)
+			if err != nil {
+				t.Errorf("%s: %v", rev, err)
+			} else if h.String() != exp {
+				t.Errorf("%s: %v != %v", rev, h, exp)
+			}
+		}
+
+		for rev, exp := range map[string][]string{
+			"HEAD": {"a 5005", "d/b 5", "d/e/c 4"},
+			"v1":   {"a 5000", "d/b 5"},
+		} {
+			h, err := r.ResolveRev(rev)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+
+			if err := r.WalkTree(h, func(path string, _ uint32, blob Hash) error {
+				bs, err := r.ReadBlob(blob)
+				if err != nil {
+					return err
+				}
+
+				got = append(got, fmt.Sprintf("%s %d", path, len(bs)))
+
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(exp, got) {
+				t.Errorf("%s: %v != %v", rev, exp, got)
+			}
+		}
+
+		ents, err := r.ReadIndex()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var paths []string
+		for _, ent := range ents {
+			paths = append(paths, ent.Path)
+
+			bs, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(ent.Path)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if h := HashObject(TypeBlob, bs); h != ent.Hash {
+				t.Errorf("%s: hash: %v != %v", ent.Path, h, ent.Hash)
+			}
+		}
+
+		sort.Strings(paths)
+
+		if exp := []string{"a", "d/b", "d/e/c"}; !reflect.DeepEqual(exp, paths) {
+			t.Errorf("%v != %v", exp, paths)
+		}
+	}
+
+	t.Run("loose", check)
+
+	// pack explicitly rather than using gc, for a predictable result.
+	objs := git(t, dir, "rev-list", "--objects", "--all")
+	gitStdin(t, dir, objs, "pack-objects", "-q", "--delta-base-offset", ".git/objects/pack/pack")
+	git(t, dir, "prune-packed")
+	git(t, dir, "pack-refs", "--all")
+	git(t, dir, "update-index", "--index-version", "4")
+
+	t.Run("packed", check)
+}
@@ -0,0 +1,353 @@
+// Package unidiff produces unified diffs of texts, line by line.
+package unidiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const noNewline = "\\ No newline at end of file\n"
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	a, b int // line positions in a and b before this op.
+}
+
+// Diff returns a unified diff transforming a into b, with n lines of context
+// around changes. An empty string is returned if a and b are equal.
+func Diff(fromName, toName string, a, b []byte, n int) string {
+	if string(a) == string(b) {
+		return ""
+	}
+
+	as, bs := splitLines(string(a)), splitLines(string(b))
+
+	ops := diff(as, bs)
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	for i := 0; i < len(ops); {
+		// find the next change.
+		for i < len(ops) && ops[i].kind == opEqual {
+			i++
+		}
+
+		if i == len(ops) {
+			break
+		}
+
+		start := i - n
+		if start < 0 {
+			start = 0
+		}
+
+		// extend the hunk while changes are close enough to share context.
+		end, equals := i, 0
+		for end < len(ops) && equals <= 2*n {
+			if ops[end].kind == opEqual {
+				equals++
+			} else {
+				equals = 0
+			}
+
+			end++
+		}
+
+		// ops[start:end] ends with equals unchanged lines, only n of which are
+		// context.
+		if equals > n {
+			end -= equals - n
+		}
+
+		writeHunk(&sb, as, bs, ops[start:end])
+
+		i = end
+	}
+
+	return sb.String()
+}
+
+// ChangedLines returns the 1-based numbers of the lines of a that are
+// deleted, and of the lines of b that are inserted, by the shortest edit
+// script from a to b. A replaced line is both deleted and inserted.
+func ChangedLines(a, b []byte) (deleted, inserted []int) {
+	for _, o := range diff(splitLines(string(a)), splitLines(string(b))) {
+		switch o.kind {
+		case opDelete:
+			deleted = append(deleted, o.a+1)
+		case opInsert:
+			inserted = append(inserted, o.b+1)
+		}
+	}
+
+	return
+}
+
+func writeHunk(sb *strings.Builder, as, bs []string, ops []op) {
+	var alen, blen int
+
+	for _, o := range ops {
+		if o.kind != opInsert {
+			alen++
+		}
+
+		if o.kind != opDelete {
+			blen++
+		}
+	}
+
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(ops[0].a, alen), hunkRange(ops[0].b, blen))
+
+	for _, o := range ops {
+		var line string
+		if o.kind == opInsert {
+			line = bs[o.b]
+		} else {
+			line = as[o.a]
+		}
+
+		sb.WriteByte(byte(o.kind))
+		sb.WriteString(line)
+
+		if !strings.HasSuffix(line, "\n") {
+			sb.WriteString("\n" + noNewline)
+		}
+	}
+}
+
+func hunkRange(pos, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", pos)
+	}
+
+	if n == 1 {
+		return fmt.Sprint(pos + 1)
+	}
+
+	return fmt.Sprintf("%d,%d", pos+1, n)
+}
+
+// splitLines splits text into lines, each keeping its line terminator.
+func splitLines(text string) []string {
+	var lines []string
+
+	for text != "" {
+		i := strings.IndexByte(text, '\n') + 1
+		if i == 0 {
+			i = len(text)
+		}
+
+		lines = append(lines, text[:i])
+		text = text[i:]
+	}
+
+	return lines
+}
+
+// diff returns the shortest edit script from a to b, using the linear space
+// variant of Myers' algorithm. Within each run of changes, deletions come
+// before insertions.
+func diff(a, b []string) []op {
+	d := differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+
+	var (
+		ops  []op
+		x, y int
+	)
+
+	for i := 0; i < len(d.kinds); {
+		if d.kinds[i] == opEqual {
+			ops = append(ops, op{kind: opEqual, a: x, b: y})
+			x, y, i = x+1, y+1, i+1
+
+			continue
+		}
+
+		dels, ins := 0, 0
+		for ; i < len(d.kinds) && d.kinds[i] != opEqual; i++ {
+			if d.kinds[i] == opDelete {
+				dels++
+			} else {
+				ins++
+			}
+		}
+
+		for j := 0; j < dels; j++ {
+			ops = append(ops, op{kind: opDelete, a: x + j, b: y})
+		}
+
+		for j := 0; j < ins; j++ {
+			ops = append(ops, op{kind: opInsert, a: x + dels, b: y + j})
+		}
+
+		x, y = x+dels, y+ins
+	}
+
+	return ops
+}
+
+// differ records the kinds of the ops of an edit script, in order.
+type differ struct {
+	a, b  []string
+	kinds []opKind
+}
+
+func (d *differ) add(kind opKind, n int) {
+	for ; n > 0; n-- {
+		d.kinds = append(d.kinds, kind)
+	}
+}
+
+// compare records the edit script from a[a0:a1] to b[b0:b1].
+func (d *differ) compare(a0, a1, b0, b1 int) {
+	prefix := 0
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		a0, b0, prefix = a0+1, b0+1, prefix+1
+	}
+
+	suffix := 0
+	for a0 < a1 && b0 < b1 && d.a[a1-1] == d.b[b1-1] {
+		a1, b1, suffix = a1-1, b1-1, suffix+1
+	}
+
+	d.add(opEqual, prefix)
+
+	if a0 == a1 || b0 == b1 || !d.anyCommon(a0, a1, b0, b1) {
+		d.add(opDelete, a1-a0)
+		d.add(opInsert, b1-b0)
+	} else if x, y, ok := d.middleSnake(a0, a1, b0, b1); ok {
+		d.compare(a0, x, b0, y)
+		d.compare(x, a1, y, b1)
+	} else {
+		d.add(opDelete, a1-a0)
+		d.add(opInsert, b1-b0)
+	}
+
+	d.add(opEqual, suffix)
+}
+
+// anyCommon returns true if a[a0:a1] and b[b0:b1] have any line in common.
+// Texts that were entirely rewritten, which would take the longest to
+// search, have none.
+func (d *differ) anyCommon(a0, a1, b0, b1 int) bool {
+	lines := make(map[string]bool, a1-a0)
+	for _, l := range d.a[a0:a1] {
+		lines[l] = true
+	}
+
+	for _, l := range d.b[b0:b1] {
+		if lines[l] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// middleSnake finds a point in the middle of a shortest edit path from
+// a[a0:a1] to b[b0:b1], by searching from both ends at once, until the
+// paths overlap. Only O(N+M) space is used, rather than the O(D*(N+M)) of
+// keeping all the paths searched. ok is false if the texts have no lines in
+// common.
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (x, y int, ok bool) {
+	n, m := a1-a0, b1-b0
+
+	max := (n + m + 1) / 2
+	off := max
+
+	// furthest x reached on each diagonal k = x - y, forwards in vf and
+	// backwards (from the ends) in vb. -1 if not reached yet.
+	vf, vb := make([]int, 2*max+2), make([]int, 2*max+2)
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+
+	vf[off+1], vb[off+1] = 0, 0
+
+	delta := n - m
+
+	// paths overlap when the forward one is extended if delta is odd, or
+	// when the backward one is otherwise.
+	front := delta%2 != 0
+
+	// diagonals that went out of the edit graph are no longer searched.
+	var fStart, fEnd, bStart, bEnd int
+
+	for dist := 0; dist < max; dist++ {
+		for k := -dist + fStart; k <= dist-fEnd; k += 2 {
+			var x int
+			if k == -dist || (k != dist && vf[off+k-1] < vf[off+k+1]) {
+				x = vf[off+k+1] // down: insertion.
+			} else {
+				x = vf[off+k-1] + 1 // right: deletion.
+			}
+
+			y := x - k
+
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+
+			vf[off+k] = x
+
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case front:
+				if kb := off + delta - k; kb >= 0 && kb < len(vb) && vb[kb] != -1 && x >= n-vb[kb] {
+					return a0 + x, b0 + y, true
+				}
+			}
+		}
+
+		for k := -dist + bStart; k <= dist-bEnd; k += 2 {
+			var x int
+			if k == -dist || (k != dist && vb[off+k-1] < vb[off+k+1]) {
+				x = vb[off+k+1]
+			} else {
+				x = vb[off+k-1] + 1
+			}
+
+			y := x - k
+
+			for x < n && y < m && d.a[a1-x-1] == d.b[b1-y-1] {
+				x++
+				y++
+			}
+
+			vb[off+k] = x
+
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !front:
+				if kf := off + delta - k; kf >= 0 && kf < len(vf) && vf[kf] != -1 {
+					fx := vf[kf]
+					fy := fx - (kf - off)
+
+					if fx >= n-x {
+						return a0 + fx, b0 + fy, true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
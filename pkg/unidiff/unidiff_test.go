@@ -0,0 +1,185 @@
+package unidiff
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		exp  string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+		},
+		{
+			name: "change",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b:    "1\n2\n3\n4\nfive\n6\n7\n8\n",
+			exp:  "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "x\n1\n2\n3\n4\n5\n6\n7\nx\n",
+			b:    "y\n1\n2\n3\n4\n5\n6\n7\ny\n",
+			exp:  "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-x\n+y\n 1\n 2\n 3\n@@ -6,4 +6,4 @@\n 5\n 6\n 7\n-x\n+y\n",
+		},
+		{
+			name: "no newline",
+			a:    "a\nb",
+			b:    "a\nc\n",
+			exp:  "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n",
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "a\n",
+			exp:  "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if d := Diff("a", "b", []byte(test.a), []byte(test.b), 3); d != test.exp {
+				t.Errorf("\n%s\n!=\n%s", d, test.exp)
+			}
+		})
+	}
+}
+
+func TestChangedLines(t *testing.T) {
+	tests := []struct {
+		name         string
+		a, b         string
+		deleted, ins []int
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+		},
+		{
+			name:    "replace",
+			a:       "1\n2\n3\n",
+			b:       "1\ntwo\n3\n",
+			deleted: []int{2},
+			ins:     []int{2},
+		},
+		{
+			name: "insert",
+			a:    "1\n3\n",
+			b:    "0\n1\n2\n3\n",
+			ins:  []int{1, 3},
+		},
+		{
+			name:    "delete",
+			a:       "1\n2\n3\n4\n",
+			b:       "1\n4\n",
+			deleted: []int{2, 3},
+		},
+		{
+			name:    "no newline",
+			a:       "1\n2",
+			b:       "1\n2\n",
+			deleted: []int{2},
+			ins:     []int{2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deleted, ins := ChangedLines([]byte(test.a), []byte(test.b))
+
+			if !reflect.DeepEqual(deleted, test.deleted) {
+				t.Errorf("deleted: %v != %v", deleted, test.deleted)
+			}
+
+			if !reflect.DeepEqual(ins, test.ins) {
+				t.Errorf("inserted: %v != %v", ins, test.ins)
+			}
+		})
+	}
+}
+
+func TestDiffShortest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	text := func() []string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+
+		return lines
+	}
+
+	// lcs returns the length of the longest common subsequence of a and b.
+	lcs := func(a, b []string) int {
+		l := make([][]int, len(a)+1)
+		for i := range l {
+			l[i] = make([]int, len(b)+1)
+		}
+
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					l[i][j] = l[i+1][j+1] + 1
+				} else if l[i+1][j] > l[i][j+1] {
+					l[i][j] = l[i+1][j]
+				} else {
+					l[i][j] = l[i][j+1]
+				}
+			}
+		}
+
+		return l[0][0]
+	}
+
+	for i := 0; i < 1000; i++ {
+		a, b := text(), text()
+
+		ops := diff(a, b)
+
+		var (
+			got   []string
+			edits int
+			x, y  int
+		)
+
+		for _, o := range ops {
+			if o.a != x || o.b != y {
+				t.Fatalf("%v -> %v: op %+v at %d,%d", a, b, o, x, y)
+			}
+
+			switch o.kind {
+			case opEqual:
+				if a[x] != b[y] {
+					t.Fatalf("%v -> %v: %d,%d are not equal", a, b, x, y)
+				}
+
+				got = append(got, a[x])
+				x, y = x+1, y+1
+			case opDelete:
+				edits++
+				x++
+			case opInsert:
+				edits++
+				got = append(got, b[y])
+				y++
+			}
+		}
+
+		if !reflect.DeepEqual(got, b) && len(got)+len(b) > 0 {
+			t.Fatalf("%v -> %v: got %v", a, b, got)
+		}
+
+		if exp := len(a) + len(b) - 2*lcs(a, b); edits != exp {
+			t.Fatalf("%v -> %v: %d edits, shortest is %d", a, b, edits, exp)
+		}
+	}
+}